@@ -0,0 +1,129 @@
+// Package aws signs requests to AWS services with Signature Version 4,
+// the AWS SDK is not vendored and stevedore makes only a couple of calls
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4         = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+)
+
+type (
+	// Credentials of the AWS identity the requests are signed with
+	Credentials struct {
+		AccessKeyID     string
+		SecretAccessKey string
+		// SessionToken is set for temporary credentials, optional
+		SessionToken string
+	}
+
+	// Config overrides how a service is reached
+	Config struct {
+		// Endpoint replaces the endpoint of the service, e.g. of an S3 compatible store, optional
+		Endpoint string
+		// Credentials replace the ones from the environment, optional
+		Credentials *Credentials
+		// HTTPClient replaces http.DefaultClient, optional
+		HTTPClient *http.Client
+	}
+)
+
+// CredentialsFromEnv reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and the optional AWS_SESSION_TOKEN
+func CredentialsFromEnv() (*Credentials, error) {
+	creds := &Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return creds, nil
+}
+
+// Sign adds the Signature Version 4 authorization of the request for the service, the host, content-type
+// and all the x-amz-* headers are signed. X-Amz-Date and X-Amz-Security-Token are set here.
+func Sign(req *http.Request, payload []byte, creds *Credentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	if v := req.Header.Get("Content-Type"); v != "" {
+		headers["content-type"] = v
+	}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = req.Header.Get(name)
+		}
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + strings.TrimSpace(headers[name]) + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req),
+		canonicalHeaders,
+		signedHeaders,
+		HexSHA256(payload),
+	}, "\n")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		sigV4,
+		amzDate,
+		scope,
+		HexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", sigV4, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery sorts the query parameters by name, the values are escaped by url.Values
+func canonicalQuery(req *http.Request) string {
+	return strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+}
+
+// HexSHA256 is the hash of the payload, S3 requires it in the X-Amz-Content-Sha256 header too
+func HexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/codefresh-io/stevedore/stevedore"
 	log "github.com/sirupsen/logrus"
@@ -25,56 +26,77 @@ func setupCommands(app *cli.App) {
 			Name:        "create",
 			Description: "Create clusters in Codefresh. Default is to add current-context",
 			Action:      stevedore.Init,
-			Before: func(c *cli.Context) error {
-				log.SetLevel(log.FatalLevel)
-				log.SetFormatter(&log.TextFormatter{})
-				if c.IsSet("verbose") {
-					log.SetLevel(log.InfoLevel)
-				}
-				return nil
-			},
-			Flags: []cli.Flag{
-				cli.BoolFlag{
-					Name:  "verbose, v",
-					Usage: "Turn on verbose mode",
-				},
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
 				cli.BoolFlag{
 					Name:  "all, a",
 					Usage: "Add all clusters from config file, default is only current context",
 				},
+				cli.BoolFlag{
+					Name:  "group-by-server",
+					Usage: "Reuse a single client for all the contexts of the same server, with the credentials of the first of them (only with --all)",
+				},
 				cli.StringFlag{
 					Name:  "context, c",
 					Usage: "Add spesific cluster",
 				},
+				cli.IntFlag{
+					Name:  "config-load-attempts",
+					Usage: "How many times to try loading --config, in case it is still being written (default: 3)",
+				},
+				cli.DurationFlag{
+					Name:  "config-load-delay",
+					Usage: "Time to wait between the attempts of loading --config (default: 1s)",
+				},
+				cli.BoolFlag{
+					Name:  "watch-config",
+					Usage: "Reload --config when it is rewritten during the run, e.g. when tokens are rotated, the contexts not started yet use the new config. The file is checked every 2s",
+				},
+				cli.StringFlag{
+					Name:  "config-ssm-parameter",
+					Usage: "Read the kubeconfig from this AWS SSM parameter instead of --config, the AWS credentials are taken from the environment",
+				},
 				cli.StringFlag{
-					Name:   "token",
-					Usage:  "Codefresh token",
-					EnvVar: "CODEFRESH_TOKEN",
+					Name:   "aws-region",
+					Usage:  "AWS region of --config-ssm-parameter and --s3-bucket",
+					EnvVar: "AWS_REGION",
 				},
 				cli.StringFlag{
-					Name:   "config",
-					Usage:  "Kubernetes config file to be used as input",
-					Value:  fmt.Sprintf("%s/.kube/config", os.Getenv("HOME")),
-					EnvVar: "KUBECONFIG",
+					Name:  "output-contexts",
+					Usage: "Print the contexts with the names, namespaces and service accounts they would be added with, as yaml, json or table, and exit without adding any",
 				},
 				cli.StringFlag{
-					Name:   "api-host",
-					Usage:  "Codefresh API host",
-					Value:  "https://g.codefresh.io/",
-					EnvVar: "CODEFRESH_URL",
+					Name:  "contexts-file",
+					Usage: "Add the contexts listed in a file, one name per line, lines starting with # are ignored",
 				},
 				cli.StringFlag{
 					Name:   "namespace",
-					Usage:  "Which namespace to use while adding cluster to Codefresh (only with --context)",
+					Usage:  "Which namespace to use while adding cluster to Codefresh",
 					Value:  "default",
 					EnvVar: "NAMESPACE",
 				},
 				cli.StringFlag{
 					Name:   "serviceaccount",
-					Usage:  "Which service account to use while adding cluster to Codefresh (only with --context)",
+					Usage:  "Which service account to use while adding cluster to Codefresh, a comma separated list is tried in order and the first one with a token secret is used",
 					Value:  "default",
 					EnvVar: "SERVICE_ACCOUNT",
 				},
+				cli.BoolFlag{
+					Name:  "create-serviceaccount",
+					Usage: "Create the service account when it does not exist, bound to a new cluster role granting every verb on every resource and non-resource URL, which is full cluster-admin access, unless --serviceaccount-cluster-role is set",
+				},
+				cli.StringFlag{
+					Name:  "serviceaccount-cluster-role",
+					Usage: "Existing cluster role to bind the service accounts created by --create-serviceaccount to, e.g. edit, instead of creating a cluster role with full access",
+				},
+				cli.BoolFlag{
+					Name:  "cleanup-on-failure",
+					Usage: "Delete the service account and its role created by --create-serviceaccount when the context fails",
+				},
+				cli.StringFlag{
+					Name:  "events-namespace",
+					Usage: "Record a Kubernetes event per context in this namespace of the cluster stevedore runs in",
+				},
 				cli.BoolFlag{
 					Name:  "behind-firewall, b",
 					Usage: "Spesify whenever the cluster is behined firewall (only with --context)",
@@ -84,7 +106,619 @@ func setupCommands(app *cli.App) {
 					Usage:  "Spesify under which name save the cluster in Codefresh, default is the same name as the context (only with --context)",
 					EnvVar: "NAME_OVERWRITE",
 				},
+				cli.StringSliceFlag{
+					Name:  "team",
+					Usage: "Assign the added clusters to a Codefresh team, can be passed multiple times",
+				},
+				cli.StringSliceFlag{
+					Name:  "target",
+					Usage: "Add each cluster once more as <name>-<team> for the team, scoped to the namespaces, in the form of <team>=<namespace>,..., can be passed multiple times",
+				},
+				cli.StringFlag{
+					Name:  "storage-class",
+					Usage: "Storage class to be used for the build volumes of the added clusters",
+				},
+				cli.StringFlag{
+					Name:  "reclaim-policy",
+					Usage: "Reclaim policy to be used for the build volumes of the added clusters",
+				},
+				cli.StringFlag{
+					Name:  "agent-namespace",
+					Usage: "Namespace to install the Codefresh agent in for clusters behind firewall",
+				},
+				cli.StringFlag{
+					Name:  "agent-serviceaccount",
+					Usage: "Service account of --agent-namespace for the Codefresh agent to run with, independent from --serviceaccount the token is taken from",
+				},
+				cli.StringFlag{
+					Name:   "overrides",
+					Usage:  "YAML file with per context settings that take precedence over the flags",
+					EnvVar: "OVERRIDES",
+				},
+				cli.DurationFlag{
+					Name:  "context-timeout",
+					Usage: "Maximum time to spend on a single context, retries included (0 means no limit)",
+				},
+				cli.DurationFlag{
+					Name:  "run-deadline",
+					Usage: "Maximum time of the whole run, contexts not started by then are skipped (0 means no limit)",
+				},
+				cli.BoolFlag{
+					Name:  "cancel-in-flight",
+					Usage: "Cancel the context in progress when the run deadline is exceeded instead of letting it finish",
+				},
+				cli.IntFlag{
+					Name:  "workers",
+					Usage: "Number of contexts to process in parallel, context i of the sorted list always goes to worker i mod workers, values above 1 take precedence over --max-concurrency (only with --all)",
+					Value: 1,
+				},
+				cli.IntFlag{
+					Name:  "max-concurrency",
+					Usage: "Maximum number of contexts to process in parallel, 10 by default, 1 processes them one at a time as before, --workers above 1 takes precedence (only with --all)",
+					Value: 10,
+				},
+				cli.BoolFlag{
+					Name:  "shuffle",
+					Usage: "Process the contexts in random order instead of sorted by name, the seed is logged (only with --all)",
+				},
+				cli.Int64Flag{
+					Name:  "shuffle-seed",
+					Usage: "Seed of --shuffle to get the same order on every run (0 means a random seed)",
+				},
+				cli.BoolFlag{
+					Name:  "check-ca-consistency",
+					Usage: "Warn about contexts of the same host that use different CAs (only with --all)",
+				},
+				cli.StringSliceFlag{
+					Name:  "insecure-host",
+					Usage: "API server URL or host name to skip the TLS verification of, can be passed multiple times",
+				},
+				cli.StringFlag{
+					Name:  "server-overrides",
+					Usage: "YAML file mapping regular expressions of API server URLs to their replacements, e.g. for internal DNS names that are not routable",
+				},
+				cli.StringFlag{
+					Name:  "ca-bundle",
+					Usage: "PEM file of CAs to trust in addition to the CA of each cluster when connecting to it, e.g. of a TLS intercepting proxy",
+				},
+				cli.StringFlag{
+					Name:  "smoke-test-pipeline",
+					Usage: "Name or id of a Codefresh pipeline to run against every added cluster, the cluster name is passed as CLUSTER_NAME",
+				},
+				cli.DurationFlag{
+					Name:  "smoke-test-timeout",
+					Usage: "Maximum time to wait for the smoke test build of a cluster (default: 10m)",
+				},
+				cli.BoolFlag{
+					Name:  "check-runner",
+					Usage: "Wait for the Codefresh runner to reach each added behind firewall cluster, reported as RUNNER_CONNECTED or RUNNER_PENDING",
+				},
+				cli.DurationFlag{
+					Name:  "check-runner-timeout",
+					Value: 2 * time.Minute,
+					Usage: "Maximum time to wait for the runner to reach a cluster",
+				},
+				cli.BoolFlag{
+					Name:  "write-results",
+					Usage: "Write the Codefresh id of each added cluster to the stevedore-results ConfigMap of the cluster stevedore runs in (only with --all)",
+				},
+				cli.StringFlag{
+					Name:  "results-namespace",
+					Usage: "Namespace of the stevedore-results ConfigMap, default is the namespace stevedore runs in",
+				},
+				cli.BoolFlag{
+					Name:  "annotate-kubeconfig",
+					Usage: "Write the registration time and the Codefresh id of each added cluster back to its context in the kubeconfig file, the original is kept as <config>.bak (only with --all)",
+				},
+				cli.StringFlag{
+					Name:  "name-pattern",
+					Usage: "Regular expression the Codefresh names must match, e.g. ^[a-z0-9-]+$, contexts violating it are reported as INVALID_NAME",
+				},
+				cli.IntFlag{
+					Name:  "name-max-length",
+					Usage: "Maximum length of the Codefresh names (0 means no limit)",
+				},
+				cli.BoolFlag{
+					Name:  "refresh-tokens",
+					Usage: "Update the clusters that already exist in Codefresh but are not reachable with their stored token, e.g. after it expired",
+				},
+				cli.BoolFlag{
+					Name:  "strict-config",
+					Usage: "Fail the contexts with invalid kubeconfig entries (missing cluster or user, unreadable files, invalid certificate data) instead of only warning about them (only with --all)",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude-context",
+					Usage: "Name of a context to never add, can be passed multiple times (only with --all)",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude-context-pattern",
+					Usage: "Glob pattern of contexts to never add (e.g. minikube*), can be passed multiple times (only with --all)",
+				},
+				cli.IntFlag{
+					Name:  "retries",
+					Usage: "How many times to retry a failed context",
+				},
+				cli.DurationFlag{
+					Name:  "retry-delay",
+					Usage: "Time to wait between retries",
+					Value: time.Second,
+				},
+				cli.StringSliceFlag{
+					Name:  "include-provider",
+					Usage: "Add only clusters run by the provider detected from the live cluster (eks, gke, aks, unknown), can be passed multiple times",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude-provider",
+					Usage: "Skip clusters run by the provider detected from the live cluster (eks, gke, aks, unknown), can be passed multiple times",
+				},
+				cli.StringFlag{
+					Name:   "log-dir",
+					Usage:  "Directory to write a separate log file per context to, in addition to the main output (use with --verbose)",
+					EnvVar: "LOG_DIR",
+				},
+				cli.StringFlag{
+					Name:  "log-mode",
+					Usage: "How the logs of the contexts are written, streaming as they happen or batched per context once it is done",
+					Value: "streaming",
+				},
+				cli.StringFlag{
+					Name:   "name-map",
+					Usage:  "YAML file mapping context names to the names to save the clusters under in Codefresh (only with --all)",
+					EnvVar: "NAME_MAP",
+				},
+				cli.StringFlag{
+					Name:   "namespace-map",
+					Usage:  "YAML file mapping context names to the namespaces of their service accounts, unmapped contexts use --namespace (only with --all)",
+					EnvVar: "NAMESPACE_MAP",
+				},
+				cli.StringFlag{
+					Name:  "context-env-prefix",
+					Usage: "Read the namespace and the service account of each context from <prefix>_<CONTEXT>_NAMESPACE and <prefix>_<CONTEXT>_SERVICEACCOUNT, the context name uppercased with - as _ (only with --all)",
+				},
+				cli.Int64Flag{
+					Name:  "token-expiry-seconds",
+					Usage: "Lifetime of the token requested for service accounts without a token secret (0 means the API server default)",
+				},
+				cli.StringFlag{
+					Name:  "token-audience",
+					Usage: "Audience of the API servers (e.g. https://kubernetes.default.svc), service account tokens minted for other audiences are reported",
+				},
+				cli.BoolFlag{
+					Name:  "strict-token-audience",
+					Usage: "Fail the contexts whose service account token does not include --token-audience instead of warning",
+				},
+				cli.StringFlag{
+					Name:   "state-file",
+					Usage:  "File to keep fingerprints of the added clusters in, unchanged clusters are not written to Codefresh again",
+					EnvVar: "STATE_FILE",
+				},
+				cli.StringFlag{
+					Name:  "secret-field-selector",
+					Usage: "Field selector ANDed with type=kubernetes.io/service-account-token when listing the token secrets of a service account with several secrets",
+				},
+				cli.BoolFlag{
+					Name:  "node-count",
+					Usage: "Add the number of nodes of each cluster to the report",
+				},
+				cli.BoolFlag{
+					Name:  "preflight-check-token",
+					Usage: "Check that Codefresh accepts the token before processing the contexts, the run fails otherwise (only with --all)",
+				},
+				cli.IntFlag{
+					Name:  "preflight-min-contexts",
+					Usage: "Fail the run before processing the contexts when the kubeconfig has fewer contexts (only with --all)",
+				},
+				cli.StringFlag{
+					Name:  "in-cluster-token-file",
+					Usage: "Token file of the service account stevedore runs with, used when a context falls back to the in-cluster config (default: /var/run/secrets/kubernetes.io/serviceaccount/token)",
+				},
+				cli.StringFlag{
+					Name:  "in-cluster-ca-file",
+					Usage: "CA file of the cluster stevedore runs in, used when a context falls back to the in-cluster config (default: /var/run/secrets/kubernetes.io/serviceaccount/ca.crt)",
+				},
+				cli.StringFlag{
+					Name:  "timing-cache",
+					Usage: "File to keep the average time spent on a context in, used to estimate the duration of the next run (only with --all)",
+				},
+				cli.BoolFlag{
+					Name:  "debug",
+					Usage: "Write the state of each context to stevedore-debug-<run-id>-<context>.json, tokens are masked",
+				},
+				cli.BoolFlag{
+					Name:  "overwrite",
+					Usage: "Update clusters that already exist in Codefresh instead of failing",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Create the clients and read the service accounts and their tokens without adding the clusters to Codefresh, the contexts are reported as DRY_RUN",
+				},
+				cli.StringFlag{
+					Name:  "rename-from",
+					Usage: "Delete the cluster registered in Codefresh under this name before adding the context, requires --context without --all or --contexts-file. Destructive: Codefresh re-syncs the cluster",
+				},
+				cli.StringSliceFlag{
+					Name:  "redact",
+					Usage: "Regular expression to mask in the printed report (e.g. account ids), can be passed multiple times",
+				},
+				cli.StringFlag{
+					Name:  "report-only",
+					Usage: "Print only the contexts that are failed, succeeded or skipped",
+				},
+				cli.StringFlag{
+					Name:  "report-format",
+					Usage: "Format of the printed report, text, json or junit",
+					Value: "text",
+				},
+				cli.StringSliceFlag{
+					Name:  "output",
+					Usage: "Write the report to <format>=<path>, path - is stdout, e.g. text=- and json=report.json, can be passed multiple times and replaces --report-format",
+				},
+				cli.BoolFlag{
+					Name:  "progress",
+					Usage: "Show a progress bar while going over the contexts, only when the output is a terminal (only with --all)",
+				},
+				cli.StringFlag{
+					Name:   "slack-webhook",
+					Usage:  "Slack incoming webhook URL to post the summary of the run to",
+					EnvVar: "SLACK_WEBHOOK",
+				},
+				cli.StringFlag{
+					Name:  "slack-mention",
+					Usage: "Slack user (U...) or user group (S...) id to mention when any context failed",
+				},
+				cli.StringFlag{
+					Name:   "run-log-url",
+					Usage:  "Link to the log of the run to include in the Slack message",
+					EnvVar: "RUN_LOG_URL",
+				},
+				cli.StringFlag{
+					Name:  "webhook-url",
+					Usage: "URL to POST every report entry to as JSON as soon as the context is done",
+				},
+				cli.StringFlag{
+					Name:   "webhook-token",
+					Usage:  "Bearer token of the report webhook",
+					EnvVar: "WEBHOOK_TOKEN",
+				},
+				cli.IntFlag{
+					Name:  "webhook-buffer-size",
+					Value: 100,
+					Usage: "Number of failed webhook events retried on exit, the oldest are dropped",
+				},
+				cli.DurationFlag{
+					Name:  "webhook-timeout",
+					Value: 10 * time.Second,
+					Usage: "Timeout of a single webhook request",
+				},
+				cli.StringFlag{
+					Name:  "gcs-bucket",
+					Usage: "GCS bucket to upload the JSON report of the run to, the report is uploaded only when set",
+				},
+				cli.StringFlag{
+					Name:  "gcs-object-path",
+					Usage: "Prefix of the report object in --gcs-bucket, the object is named <prefix>/<start time>-<run id>.json (default: stevedore-reports)",
+				},
+				cli.StringFlag{
+					Name:   "gcs-credentials",
+					Usage:  "JSON key of the service account to upload the report with, Application Default Credentials are used when not set",
+					EnvVar: "GCS_CREDENTIALS",
+				},
+				cli.StringFlag{
+					Name:  "s3-bucket",
+					Usage: "S3 bucket in --aws-region to upload the JSON report of the run to, the report is uploaded only when set",
+				},
+				cli.StringFlag{
+					Name:  "s3-key-prefix",
+					Usage: "Prefix of the report object in --s3-bucket, the object is named <prefix>/<run id>.json",
+					Value: "stevedore-reports",
+				},
+				cli.StringFlag{
+					Name:  "s3-acl",
+					Usage: "Canned ACL of the report object",
+					Value: "private",
+				},
+				cli.BoolFlag{
+					Name:  "s3-sse",
+					Usage: "Encrypt the report object with SSE-S3",
+				},
+				cli.StringFlag{
+					Name:  "s3-endpoint",
+					Usage: "Endpoint of an S3 compatible store to upload the report to instead of AWS, e.g. https://minio.example.com",
+				},
+				cli.StringFlag{
+					Name:   "run-id",
+					Usage:  "Id of the run, included in the logs and the uploaded report, generated when not set",
+					EnvVar: "RUN_ID",
+				},
+				cli.DurationFlag{
+					Name:  "report-flush-timeout",
+					Usage: "How long to wait for the report to be sent to Slack or by email before giving up, no limit when not set",
+				},
+				cli.StringFlag{
+					Name:  "email-smtp-host",
+					Usage: "SMTP server to send a digest of the run through, the digest is sent only when set",
+				},
+				cli.StringFlag{
+					Name:  "email-smtp-port",
+					Usage: "Port of the SMTP server",
+					Value: "587",
+				},
+				cli.BoolFlag{
+					Name:  "email-tls",
+					Usage: "Connect to the SMTP server with TLS right away instead of STARTTLS (e.g. port 465)",
+				},
+				cli.StringFlag{
+					Name:   "email-username",
+					Usage:  "Username to authenticate to the SMTP server with",
+					EnvVar: "SMTP_USERNAME",
+				},
+				cli.StringFlag{
+					Name:   "email-password",
+					Usage:  "Password to authenticate to the SMTP server with",
+					EnvVar: "SMTP_PASSWORD",
+				},
+				cli.StringFlag{
+					Name:  "email-from",
+					Usage: "Sender of the digest",
+				},
+				cli.StringFlag{
+					Name:  "email-to",
+					Usage: "Comma separated recipients of the digest",
+				},
+				cli.StringFlag{
+					Name:  "email-subject",
+					Usage: "Subject of the digest, followed by the number of contexts and failures",
+					Value: "Stevedore run",
+				},
+				cli.Float64Flag{
+					Name:  "min-success-ratio",
+					Usage: "Part of the contexts that must be added for the run to succeed, skipped contexts are not counted",
+					Value: 1,
+				},
+				cli.StringFlag{
+					Name:  "cluster-info",
+					Usage: "ConfigMap in the form of <namespace>/<name> to read from each cluster and save as Codefresh tags",
+				},
+				cli.StringFlag{
+					Name:  "cluster-info-description-key",
+					Usage: "Key of the cluster info ConfigMap to save as the description of the cluster instead of a tag",
+					Value: "description",
+				},
+			),
+		},
+		{
+			Name:        "create-pipelines",
+			Description: "Create pipelines in Codefresh for all the contexts from a template",
+			Action:      stevedore.CreatePipelines,
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
+				cli.StringFlag{
+					Name:  "template",
+					Usage: "Pipeline YAML template, {{.ClusterName}} is replaced with the name of the cluster as a quoted YAML string",
+				},
+				cli.StringFlag{
+					Name:   "name-map",
+					Usage:  "YAML file mapping context names to the names the clusters are saved under in Codefresh",
+					EnvVar: "NAME_MAP",
+				},
+			),
+		},
+		{
+			Name:        "orphans",
+			Description: "Report clusters in Codefresh that have no context in the kubeconfig",
+			Action:      stevedore.ReportOrphans,
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
+				cli.BoolFlag{
+					Name:  "delete",
+					Usage: "Delete the orphaned clusters from Codefresh",
+				},
+				cli.StringFlag{
+					Name:   "name-map",
+					Usage:  "YAML file mapping context names to the names the clusters are saved under in Codefresh",
+					EnvVar: "NAME_MAP",
+				},
+			),
+		},
+		{
+			Name:        "delete-all",
+			Description: "Delete the clusters of all the contexts of the kubeconfig from Codefresh",
+			Action:      stevedore.DeleteAll,
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
+				cli.DurationFlag{
+					Name:  "interval",
+					Value: time.Second,
+					Usage: "Minimal time between two deletions, to stay under the rate limit of Codefresh",
+				},
+				cli.IntFlag{
+					Name:  "retries",
+					Usage: "How many times to retry a failed deletion",
+				},
+				cli.DurationFlag{
+					Name:  "retry-delay",
+					Value: 5 * time.Second,
+					Usage: "Time to wait between the attempts of a deletion",
+				},
+				cli.StringFlag{
+					Name:  "checkpoint",
+					Usage: "File to record the deleted clusters in, a run with the same file resumes where the previous one stopped",
+				},
+				cli.StringFlag{
+					Name:   "name-map",
+					Usage:  "YAML file mapping context names to the names the clusters are saved under in Codefresh",
+					EnvVar: "NAME_MAP",
+				},
+			),
+		},
+		{
+			Name:        "diff",
+			ArgsUsage:   "<previous report> <current report>",
+			Description: "Compare two JSON reports and print the contexts that changed status as JSON",
+			Action:      stevedore.DiffReports,
+			Before:      setupLogger,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "fail-on-regression",
+					Usage: "Exit with an error when any context started failing",
+				},
 			},
 		},
+		{
+			Name:        "export",
+			Description: "Export the clusters registered in Codefresh as a kubeconfig",
+			Action:      stevedore.Export,
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "File to write the kubeconfig to, default is stdout",
+				},
+			),
+		},
+		{
+			Name:        "agents",
+			Description: "Check the status of the Codefresh agent of every registered cluster",
+			Action:      stevedore.CheckAgents,
+			Before:      setupLogger,
+			Flags:       commonFlags(),
+		},
+		{
+			Name:        "environments",
+			Description: "Create a Codefresh environment for each namespace of a cluster that is already in Codefresh",
+			Action:      stevedore.CreateEnvironments,
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
+				cli.StringFlag{
+					Name:  "context, c",
+					Usage: "Context of the cluster",
+				},
+				cli.StringFlag{
+					Name:  "namespace-selector",
+					Usage: "Label selector of the namespaces, default is all namespaces",
+				},
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "Name of the cluster in Codefresh, default is the context name",
+				},
+				cli.StringFlag{
+					Name:  "prefix",
+					Usage: "Prefix of the environment names, the namespace name is appended to it",
+				},
+			),
+		},
+		{
+			Name:        "operator",
+			Description: "Run in the cluster and add the contexts declared by ClusterRegistration objects to Codefresh, see pkg/kubernetes/crd",
+			Action:      stevedore.RunOperator,
+			Before:      setupLogger,
+			Flags: append(commonFlags(),
+				cli.StringFlag{
+					Name:  "watch-namespace",
+					Usage: "Namespace of the ClusterRegistration objects, default is all namespaces",
+				},
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "Time between two passes over the ClusterRegistration objects",
+					Value: 30 * time.Second,
+				},
+			),
+		},
+	}
+}
+
+func setupLogger(c *cli.Context) error {
+	log.SetLevel(log.FatalLevel)
+	log.SetFormatter(&log.TextFormatter{})
+	if c.IsSet("verbose") {
+		log.SetLevel(log.InfoLevel)
+	}
+	return nil
+}
+
+func commonFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "verbose, v",
+			Usage: "Turn on verbose mode",
+		},
+		cli.StringFlag{
+			Name:   "token",
+			Usage:  "Codefresh token",
+			EnvVar: "CODEFRESH_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "Kubernetes config file to be used as input",
+			Value:  fmt.Sprintf("%s/.kube/config", os.Getenv("HOME")),
+			EnvVar: "KUBECONFIG",
+		},
+		cli.StringFlag{
+			Name:   "api-host",
+			Usage:  "Codefresh API host",
+			Value:  "https://g.codefresh.io/",
+			EnvVar: "CODEFRESH_URL",
+		},
+		cli.DurationFlag{
+			Name:  "api-timeout",
+			Usage: "Timeout of a single Codefresh API request (0 means no limit)",
+		},
+		cli.DurationFlag{
+			Name:  "api-dial-timeout",
+			Usage: "Timeout of establishing a connection to Codefresh API (default: 30s)",
+		},
+		cli.DurationFlag{
+			Name:  "api-tls-handshake-timeout",
+			Usage: "Timeout of the TLS handshake with Codefresh API (default: 10s)",
+		},
+		cli.DurationFlag{
+			Name:  "api-keep-alive",
+			Usage: "Keep-alive period of the connections to Codefresh API (default: 30s)",
+		},
+		cli.DurationFlag{
+			Name:  "api-idle-conn-timeout",
+			Usage: "Time after which idle connections to Codefresh API are closed (default: 90s)",
+		},
+		cli.StringFlag{
+			Name:  "api-version",
+			Usage: "Codefresh platform to add the clusters to, v1 or v2 (the new platform, --token is an Argo CD API token)",
+			Value: "v1",
+		},
+		cli.StringFlag{
+			Name:   "api-version-header",
+			Usage:  "Codefresh API version to send in the API-Version header, default is the current version of the server",
+			EnvVar: "CODEFRESH_API_VERSION",
+		},
+		cli.BoolFlag{
+			Name:  "api-async",
+			Usage: "Submit the clusters to Codefresh as jobs and poll them until they are done",
+		},
+		cli.IntFlag{
+			Name:  "api-clients",
+			Usage: "Number of independent Codefresh API clients to spread the requests between",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  "api-debug-http",
+			Usage: "Log every Codefresh API request and its response, the token is redacted (requires --verbose)",
+		},
+		cli.IntFlag{
+			Name:  "api-rate-limit-low-watermark",
+			Usage: "Wait for the Codefresh API rate limit to reset once the remaining budget drops to this value",
+		},
+		cli.StringFlag{
+			Name:   "api-client-cert",
+			Usage:  "PEM client certificate to present to Codefresh API, for installations that require mTLS",
+			EnvVar: "CODEFRESH_CLIENT_CERT",
+		},
+		cli.StringFlag{
+			Name:   "api-client-key",
+			Usage:  "PEM private key of --api-client-cert",
+			EnvVar: "CODEFRESH_CLIENT_KEY",
+		},
+		cli.StringFlag{
+			Name:  "api-config-map",
+			Usage: "Read Codefresh API options from <namespace>/<name> ConfigMap of the cluster stevedore runs in, replaces the api flags",
+		},
 	}
 }
@@ -0,0 +1,62 @@
+package codefresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// AgentStatusHealthy is the status of an agent that is connected to Codefresh
+const AgentStatusHealthy = "healthy"
+
+// AgentStatus is the state of the Codefresh agent installed in a registered cluster
+type AgentStatus struct {
+	Version       string    `json:"version"`
+	Status        string    `json:"status"`
+	LastHeartbeat time.Time `json:"lastHeartbeat"`
+}
+
+// GetAgentStatus returns the state of the agent of the cluster with the given name
+func (api *codefreshAPI) GetAgentStatus(ctx context.Context, clusterName string) (*AgentStatus, error) {
+	body, status, err := api.do(ctx, "GET", fmt.Sprintf("api/clusters/local/cluster/%s/agent", url.PathEscape(clusterName)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to get agent status %s", err)
+	}
+	agent := &AgentStatus{}
+	err = json.Unmarshal(body, agent)
+	if err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+// RunnerStatus is whether the Codefresh runner of a behind firewall cluster reaches the API server of the cluster
+type RunnerStatus struct {
+	Connected bool   `json:"connected"`
+	Message   string `json:"message"`
+}
+
+// GetRunnerStatus returns the connectivity of the runner to the cluster with the given name
+func (api *codefreshAPI) GetRunnerStatus(ctx context.Context, clusterName string) (*RunnerStatus, error) {
+	body, status, err := api.do(ctx, "GET", fmt.Sprintf("api/clusters/local/cluster/%s/runner/connectivity", url.PathEscape(clusterName)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to get runner status %s", err)
+	}
+	runner := &RunnerStatus{}
+	err = json.Unmarshal(body, runner)
+	if err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
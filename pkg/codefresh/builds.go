@@ -0,0 +1,97 @@
+package codefresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	BuildSucceeded = "success"
+
+	buildPollInterval   = 5 * time.Second
+	defaultBuildTimeout = 10 * time.Minute
+)
+
+type (
+	// SmokeTestOptions describes the pipeline run against a newly added cluster
+	SmokeTestOptions struct {
+		// Pipeline is the name or id of an existing pipeline, the cluster name is passed
+		// to it as the CLUSTER_NAME variable
+		Pipeline    string
+		ClusterName string
+	}
+
+	runPipelinePayload struct {
+		Variables map[string]string `json:"variables"`
+	}
+
+	buildStatusResponse struct {
+		Status string `json:"status"`
+	}
+)
+
+// finished build statuses other than success
+var failedBuildStatuses = map[string]bool{
+	"error":      true,
+	"terminated": true,
+	"denied":     true,
+}
+
+// RunSmokeTest runs the pipeline for the cluster and waits until the build succeeded or failed.
+// The build is waited for until the deadline of the context, or 10 minutes when it has none.
+func (api *codefreshAPI) RunSmokeTest(ctx context.Context, opt SmokeTestOptions) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultBuildTimeout)
+		defer cancel()
+	}
+	body, status, err := api.do(ctx, "POST", "api/pipelines/run/"+url.PathEscape(opt.Pipeline), &runPipelinePayload{
+		Variables: map[string]string{
+			"CLUSTER_NAME": opt.ClusterName,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if status != 200 && status != 201 {
+		err := errors.New(string(body))
+		return fmt.Errorf("Failed to run pipeline %s", err)
+	}
+	buildID := ""
+	err = json.Unmarshal(body, &buildID)
+	if err != nil || buildID == "" {
+		return fmt.Errorf("Build id is missing in Codefresh response %s", string(body))
+	}
+	for {
+		body, status, err := api.do(ctx, "GET", "api/builds/"+url.PathEscape(buildID), nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("Timed out waiting for build %s", buildID)
+			}
+			return err
+		}
+		if status != 200 {
+			return fmt.Errorf("Failed to get status of build %s %s", buildID, string(body))
+		}
+		build := &buildStatusResponse{}
+		err = json.Unmarshal(body, build)
+		if err != nil {
+			return err
+		}
+		if build.Status == BuildSucceeded {
+			return nil
+		}
+		if failedBuildStatuses[build.Status] {
+			return fmt.Errorf("Build %s finished with status %s", buildID, build.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Timed out waiting for build %s", buildID)
+		case <-time.After(buildPollInterval):
+		}
+	}
+}
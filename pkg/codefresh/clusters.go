@@ -0,0 +1,101 @@
+package codefresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Cluster is a cluster registered in Codefresh, the credentials are returned only by GetCluster
+type Cluster struct {
+	ID                  string `json:"_id"`
+	Selector            string `json:"selector"`
+	Host                string `json:"host"`
+	BehindFirewall      bool   `json:"behindFirewall"`
+	ClientCa            []byte `json:"clientCa,omitempty"`
+	ServiceAccountToken []byte `json:"serviceAccountToken,omitempty"`
+}
+
+// List returns all the clusters registered in the account
+func (api *codefreshAPI) List(ctx context.Context) ([]Cluster, error) {
+	body, status, err := api.do(ctx, "GET", "api/clusters/local/cluster", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to list clusters %s", err)
+	}
+	clusters := []Cluster{}
+	err = json.Unmarshal(body, &clusters)
+	if err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// GetCluster returns the cluster with the given name including its credentials
+func (api *codefreshAPI) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	body, status, err := api.do(ctx, "GET", "api/clusters/local/cluster/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to get cluster %s", err)
+	}
+	cluster := &Cluster{}
+	err = json.Unmarshal(body, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// Delete removes the cluster with the given name from the account
+func (api *codefreshAPI) Delete(ctx context.Context, name string) error {
+	body, status, err := api.do(ctx, "DELETE", "api/clusters/local/cluster/"+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	if status != 200 && status != 204 {
+		err := errors.New(string(body))
+		return fmt.Errorf("Failed to delete cluster %s", err)
+	}
+	return nil
+}
+
+// ClusterID returns the id of the cluster from the response of Create or PatchCluster,
+// empty when the response has no cluster id, e.g. in async mode
+func ClusterID(created []byte) string {
+	cluster := &clusterResponse{}
+	err := json.Unmarshal(created, cluster)
+	if err != nil {
+		return ""
+	}
+	return cluster.ID
+}
+
+// ClusterURL returns the link to the cluster in Codefresh UI from the response of Create or PatchCluster,
+// it is empty when the response has no cluster id, e.g. in async mode
+func (api *codefreshAPI) ClusterURL(created []byte) string {
+	id := ClusterID(created)
+	if id == "" {
+		return ""
+	}
+	return api.baseURL + "account-admin/account-conf/integration/kubernetes/" + url.PathEscape(id)
+}
+
+// TestExisting checks that Codefresh can still reach the registered cluster with its stored credentials
+func TestExisting(ctx context.Context, api API, cluster *Cluster) error {
+	return api.Test(ctx, &requestPayload{
+		Type:                "sat",
+		ProviderAgent:       "custom",
+		Host:                cluster.Host,
+		Selector:            cluster.Selector,
+		ServiceAccountToken: cluster.ServiceAccountToken,
+		ClientCa:            cluster.ClientCa,
+	})
+}
@@ -1,106 +1,388 @@
 package codefresh
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"strings"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// ErrConflict is returned by Create when a cluster with the same name already exists
+var ErrConflict = errors.New("Cluster with the same name already exists in Codefresh")
+
 type (
 	API interface {
-		Test(*requestPayload) error
-		Create(string, string, []byte, []byte, bool) ([]byte, error)
+		Test(context.Context, *requestPayload) error
+		Create(context.Context, *CreateOptions) ([]byte, error)
+		PatchCluster(context.Context, *CreateOptions) ([]byte, error)
+		PollJobStatus(context.Context, string, time.Duration) ([]byte, error)
+		AssignToTeams(context.Context, []byte, []string) error
+		List(context.Context) ([]Cluster, error)
+		GetCluster(context.Context, string) (*Cluster, error)
+		Delete(context.Context, string) error
+		CreatePipeline(PipelineOptions) (string, error)
+		WhoAmI(context.Context) (*AccountInfo, error)
+		Ping(context.Context) error
+		GetAgentStatus(context.Context, string) (*AgentStatus, error)
+		GetRunnerStatus(context.Context, string) (*RunnerStatus, error)
+		ClusterURL([]byte) string
+		CreateEnvironment(context.Context, EnvironmentOptions) error
+		RunSmokeTest(context.Context, SmokeTestOptions) error
 	}
 
 	codefreshAPI struct {
-		baseURL string
-		token   string
+		baseURL    string
+		token      string
+		apiVersion string
+		// authScheme prefixes the token in the authorization header, V1 takes the bare token
+		authScheme string
+		async      bool
+		httpClient *http.Client
+
+		// limit delays the requests when the rate limit of Codefresh is almost exhausted
+		limit *rateLimit
+	}
+
+	// ClientOptions configures the client of the Codefresh API
+	ClientOptions struct {
+		BaseURL string
+		Token   string
+		// Timeout bounds a whole request, 0 means no limit
+		Timeout time.Duration
+		// DialTimeout, TLSHandshakeTimeout and HTTPKeepAlive configure the transport,
+		// the defaults of net/http are used when not set
+		DialTimeout         time.Duration
+		TLSHandshakeTimeout time.Duration
+		HTTPKeepAlive       time.Duration
+		// IdleConnTimeout closes the idle connections before load balancers drop them, default 90s
+		IdleConnTimeout time.Duration
+		// APIVersion selects the platform, v1 (default) or v2, it is unrelated to APIVersionHeader
+		APIVersion string
+		// APIVersionHeader is sent as the API-Version header of every request to pin the API version,
+		// when empty the header is not sent and the server uses its current default
+		APIVersionHeader string
+		// AsyncMode makes Create return a job id right away, use CreateAndWait to wait for the job
+		AsyncMode bool
+		// ClientCertificates are presented to Codefresh in the TLS handshake, in addition to the token
+		ClientCertificates []tls.Certificate
+		// RateLimitLowWatermark is the remaining rate limit budget at which requests wait for the limit to reset,
+		// default 0 waits only once the budget is exhausted
+		RateLimitLowWatermark int
+		// DebugHTTP logs the method, URL, status and the first 1KB of the response of every request at debug level
+		DebugHTTP bool
+	}
+
+	// CreateOptions describes a cluster to be added to Codefresh
+	CreateOptions struct {
+		Host                string
+		Name                string
+		ServiceAccountToken []byte
+		CA                  []byte
+		BehindFirewall      bool
+		// TeamNames the cluster will be assigned to by CreateOrUpdate after creation, optional
+		TeamNames []string
+		// StorageClassName and ReclaimPolicy configure the build volumes, optional
+		StorageClassName string
+		ReclaimPolicy    string
+		// Tags and Description are metadata of the cluster, optional
+		Tags        map[string]string
+		Description string
+		// AgentNamespace is where the Codefresh runtime agent is installed for clusters behind firewall,
+		// optional, Codefresh uses its default when empty
+		AgentNamespace string
+		// AgentServiceAccount is the identity the agent runs with, it may differ from the service account
+		// the token was taken from, optional
+		AgentServiceAccount string
+		// Namespaces restricts the integration to the namespaces of the cluster, optional, all namespaces when empty
+		Namespaces []string
 	}
 
 	requestPayload struct {
-		Type                string `json:"type"`
-		ClientCa            []byte `json:"clientCa"`
-		ProviderAgent       string `json:"providerAgent"`
-		Selector            string `json:"selector"`
-		ServiceAccountToken []byte `json:"serviceAccountToken"`
-		Host                string `json:"host"`
-		BehinedFirewall     bool   `json:"behindFirewall"`
+		Type                string            `json:"type"`
+		ClientCa            []byte            `json:"clientCa"`
+		ProviderAgent       string            `json:"providerAgent"`
+		Selector            string            `json:"selector"`
+		ServiceAccountToken []byte            `json:"serviceAccountToken"`
+		Host                string            `json:"host"`
+		BehinedFirewall     bool              `json:"behindFirewall"`
+		Storage             *storagePayload   `json:"storage,omitempty"`
+		Tags                map[string]string `json:"tags,omitempty"`
+		Description         string            `json:"description,omitempty"`
+		AgentNamespace      string            `json:"agentNamespace,omitempty"`
+		AgentServiceAccount string            `json:"agentServiceAccount,omitempty"`
+		Namespaces          []string          `json:"namespaces,omitempty"`
+	}
+
+	storagePayload struct {
+		StorageClassName string `json:"storageClassName,omitempty"`
+		ReclaimPolicy    string `json:"reclaimPolicy,omitempty"`
+	}
+
+	teamsPayload struct {
+		Teams []string `json:"teams"`
+	}
+
+	clusterResponse struct {
+		ID string `json:"_id"`
 	}
 )
 
-func (api *codefreshAPI) Test(payload *requestPayload) error {
-	url := api.baseURL + "api/kubernetes/test"
-	mar, _ := json.Marshal(payload)
-	p := strings.NewReader(string(mar))
-	req, err := http.NewRequest("POST", url, p)
+func (api *codefreshAPI) do(ctx context.Context, method string, path string, payload interface{}) ([]byte, int, error) {
+	err := api.limit.wait(ctx)
 	if err != nil {
-		return err
+		return nil, 0, err
+	}
+	var reader io.Reader
+	if payload != nil {
+		mar, _ := json.Marshal(payload)
+		reader = bytes.NewReader(mar)
+	}
+	req, err := http.NewRequest(method, api.baseURL+path, reader)
+	if err != nil {
+		return nil, 0, err
 	}
-	req.Header.Add("authorization", api.token)
+	req = req.WithContext(ctx)
+	req.Header.Add("authorization", api.authScheme+api.token)
 	req.Header.Add("content-type", "application/json")
-	res, err := http.DefaultClient.Do(req)
+	if api.apiVersion != "" {
+		req.Header.Add("API-Version", api.apiVersion)
+	}
+	res, err := api.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 	defer res.Body.Close()
-	_, err = ioutil.ReadAll(res.Body)
+	api.limit.update(res.Header)
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, res.StatusCode, nil
+}
+
+func (api *codefreshAPI) Test(ctx context.Context, payload *requestPayload) error {
+	_, status, err := api.do(ctx, "POST", "api/kubernetes/test", payload)
 	if err != nil {
 		return err
 	}
-	if res.StatusCode != 200 {
+	if status != 200 {
 		return errors.New("Failed to test cluster")
 	}
 	return nil
 }
 
-func (api *codefreshAPI) Create(host string, name string, saToken []byte, crt []byte, bf bool) ([]byte, error) {
-	url := api.baseURL + "api/clusters/local/cluster"
+func newRequestPayload(opt *CreateOptions) *requestPayload {
 	payload := &requestPayload{
 		Type:                "sat",
 		ProviderAgent:       "custom",
-		Host:                host,
-		Selector:            name,
-		ServiceAccountToken: saToken,
-		ClientCa:            crt,
-		BehinedFirewall:     bf,
-	}
-	mar, _ := json.Marshal(payload)
-	p := strings.NewReader(string(mar))
-	if bf == false {
-		err := api.Test(payload)
+		Host:                opt.Host,
+		Selector:            opt.Name,
+		ServiceAccountToken: opt.ServiceAccountToken,
+		ClientCa:            opt.CA,
+		BehinedFirewall:     opt.BehindFirewall,
+		Tags:                opt.Tags,
+		Description:         opt.Description,
+		AgentNamespace:      opt.AgentNamespace,
+		AgentServiceAccount: opt.AgentServiceAccount,
+		Namespaces:          opt.Namespaces,
+	}
+	if opt.StorageClassName != "" || opt.ReclaimPolicy != "" {
+		payload.Storage = &storagePayload{
+			StorageClassName: opt.StorageClassName,
+			ReclaimPolicy:    opt.ReclaimPolicy,
+		}
+	}
+	return payload
+}
+
+func (api *codefreshAPI) Create(ctx context.Context, opt *CreateOptions) ([]byte, error) {
+	payload := newRequestPayload(opt)
+	if opt.BehindFirewall == false {
+		err := api.Test(ctx, payload)
 		if err != nil {
 			return nil, err
 		}
 	}
-	req, err := http.NewRequest("POST", url, p)
+	path := "api/clusters/local/cluster"
+	if api.async {
+		path = path + "?async=true"
+	}
+	body, status, err := api.do(ctx, "POST", path, payload)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("authorization", api.token)
-	req.Header.Add("content-type", "application/json")
-	res, err := http.DefaultClient.Do(req)
+	if api.async && status == 202 {
+		jobID := JobID(body)
+		if jobID == "" {
+			return nil, fmt.Errorf("Job id is missing in Codefresh response %s", string(body))
+		}
+		return body, nil
+	}
+	if status == 409 {
+		return nil, ErrConflict
+	}
+	if status != 201 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to create cluster %s", err)
+	}
+	return body, nil
+}
+
+// PatchCluster updates the existing cluster with the same name
+func (api *codefreshAPI) PatchCluster(ctx context.Context, opt *CreateOptions) ([]byte, error) {
+	payload := newRequestPayload(opt)
+	if opt.BehindFirewall == false {
+		err := api.Test(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	body, status, err := api.do(ctx, "PATCH", "api/clusters/local/cluster/"+url.PathEscape(opt.Name), payload)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to update cluster %s", err)
+	}
+	return body, nil
+}
 
-	body, err := ioutil.ReadAll(res.Body)
+// CreateOrUpdate registers the cluster and waits for the creation job in async mode.
+// A cluster with the same name is updated when overwrite is set, otherwise ErrConflict is returned.
+// The registered cluster is then assigned to the teams of the options, a failure of that step
+// is logged as a warning as the cluster is already registered.
+func CreateOrUpdate(ctx context.Context, api API, opt *CreateOptions, overwrite bool) ([]byte, error) {
+	body, err := CreateAndWait(ctx, api, opt)
+	if err == ErrConflict && overwrite {
+		loggerFrom(ctx).Info(fmt.Sprintf("Cluster %s already exists in Codefresh, overwriting it", opt.Name))
+		body, err = api.PatchCluster(ctx, opt)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if res.StatusCode != 201 {
-		err := errors.New(string(body))
-		return nil, fmt.Errorf("Failed to create cluster %s", err)
+	if len(opt.TeamNames) > 0 {
+		err = api.AssignToTeams(ctx, body, opt.TeamNames)
+		if err != nil {
+			loggerFrom(ctx).WithFields(log.Fields{
+				"name":  opt.Name,
+				"teams": opt.TeamNames,
+			}).Warn(fmt.Sprintf("Failed to assign cluster to teams with error:\n%s", err))
+		}
 	}
 	return body, nil
 }
 
-func NewCodefreshAPI(baseUrl string, token string) API {
+// AssignToTeams assigns the cluster of the created response to the teams,
+// it is the post-create step of CreateOrUpdate
+func (api *codefreshAPI) AssignToTeams(ctx context.Context, created []byte, teams []string) error {
+	cluster := &clusterResponse{}
+	err := json.Unmarshal(created, cluster)
+	if err != nil {
+		return err
+	}
+	if cluster.ID == "" {
+		return errors.New("Cluster id is missing in Codefresh response")
+	}
+	body, status, err := api.do(ctx, "POST", fmt.Sprintf("api/clusters/local/cluster/%s/teams", cluster.ID), &teamsPayload{
+		Teams: teams,
+	})
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("Failed to assign cluster to teams %s", string(body))
+	}
+	return nil
+}
+
+// SetHTTPClient replaces the client used to call Codefresh, e.g. to inject a custom transport.
+// It is not part of the API interface, reach it with:
+//
+//	api.(interface{ SetHTTPClient(*http.Client) }).SetHTTPClient(client)
+func (api *codefreshAPI) SetHTTPClient(client *http.Client) {
+	api.httpClient = client
+}
+
+const (
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultHTTPKeepAlive       = 30 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxIdleConns        = 10
+	defaultMaxIdleConnsPerHost = 5
+)
+
+func newHTTPClient(opts ClientOptions) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultHTTPKeepAlive,
+	}
+	if opts.DialTimeout > 0 {
+		dialer.Timeout = opts.DialTimeout
+	}
+	if opts.HTTPKeepAlive > 0 {
+		dialer.KeepAlive = opts.HTTPKeepAlive
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: defaultTLSHandshakeTimeout,
+		IdleConnTimeout:     defaultIdleConnTimeout,
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+	}
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if len(opts.ClientCertificates) > 0 {
+		transport.TLSClientConfig = &tls.Config{
+			Certificates: opts.ClientCertificates,
+		}
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}
+	if opts.DebugHTTP {
+		client.Transport = &debugTransport{next: transport}
+	}
+	return client
+}
+
+func newCodefreshAPI(opts ClientOptions, limit *rateLimit) *codefreshAPI {
 	return &codefreshAPI{
-		baseURL: baseUrl,
-		token:   token,
+		baseURL:    opts.BaseURL,
+		token:      opts.Token,
+		apiVersion: opts.APIVersionHeader,
+		async:      opts.AsyncMode,
+		httpClient: newHTTPClient(opts),
+
+		limit: limit,
+	}
+}
+
+// NewCodefreshAPI creates the client of the platform selected by opts.APIVersion.
+// Empty or v1 keep the classic API, so existing callers are not affected.
+// To migrate to the new platform set APIVersion to v2, point BaseURL to its API
+// and replace Token with an Argo CD API token. Clusters are not migrated, add them again with the V2 client;
+// pipelines, environments, agents, smoke tests, teams and WhoAmI are not supported by it and return ErrNotSupportedByV2.
+func NewCodefreshAPI(opts ClientOptions) API {
+	if opts.APIVersion == APIVersionV2 {
+		return newV2Client(opts)
 	}
+	return newCodefreshAPI(opts, newRateLimit(opts.RateLimitLowWatermark))
 }
@@ -0,0 +1,73 @@
+package codefresh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// conflictServer answers Create with a conflict, the existing cluster is updated by PATCH
+func conflictServer(t *testing.T, patched *bool, teamsAssigned *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "POST" && req.URL.Path == "/api/clusters/local/cluster":
+			w.WriteHeader(http.StatusConflict)
+		case req.Method == "PATCH" && req.URL.Path == "/api/clusters/local/cluster/cluster":
+			*patched = true
+			w.Write([]byte(`{"_id": "cluster-id", "selector": "cluster"}`))
+		case req.Method == "POST" && req.URL.Path == "/api/clusters/local/cluster/cluster-id/teams":
+			*teamsAssigned = "cluster-id"
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCreateOrUpdateAssignsOverwrittenClusterToTeams(t *testing.T) {
+	patched, teamsAssigned := false, ""
+	server := conflictServer(t, &patched, &teamsAssigned)
+	defer server.Close()
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "token"})
+
+	result, err := CreateOrUpdate(context.Background(), api, &CreateOptions{
+		Name:           "cluster",
+		BehindFirewall: true,
+		TeamNames:      []string{"team"},
+	}, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !patched {
+		t.Error("expected the existing cluster to be updated")
+	}
+	if id := ClusterID(result); id != "cluster-id" {
+		t.Errorf("expected the id of the updated cluster, got %q from %s", id, result)
+	}
+	if teamsAssigned != "cluster-id" {
+		t.Error("expected the updated cluster to be assigned to the teams")
+	}
+}
+
+func TestCreateOrUpdateReturnsConflictWithoutOverwrite(t *testing.T) {
+	patched, teamsAssigned := false, ""
+	server := conflictServer(t, &patched, &teamsAssigned)
+	defer server.Close()
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "token"})
+
+	_, err := CreateOrUpdate(context.Background(), api, &CreateOptions{
+		Name:           "cluster",
+		BehindFirewall: true,
+		TeamNames:      []string{"team"},
+	}, false)
+
+	if err != ErrConflict {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if patched || teamsAssigned != "" {
+		t.Error("expected the existing cluster to be left untouched")
+	}
+}
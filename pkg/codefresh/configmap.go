@@ -0,0 +1,81 @@
+package codefresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Keys of the ConfigMap read by NewCodefreshAPIFromConfigMap
+const (
+	ConfigMapKeyBaseURL          = "base-url"
+	ConfigMapKeyToken            = "api-token"
+	ConfigMapKeyTokenSecret      = "api-token-secret"
+	ConfigMapKeyTokenSecretKey   = "api-token-secret-key"
+	ConfigMapKeyTimeoutSeconds   = "timeout-seconds"
+	ConfigMapKeyAPIVersionHeader = "api-version-header"
+	ConfigMapKeyAsyncMode        = "async-mode"
+	ConfigMapKeyAPIVersion       = "api-version"
+
+	defaultTokenSecretKey = "token"
+)
+
+// NewCodefreshAPIFromConfigMap creates the client from the ConfigMap namespace/name.
+// The token is taken from api-token, or from the key api-token-secret-key (default "token")
+// of the Secret named by api-token-secret in the same namespace, so it does not have to be kept in the ConfigMap.
+func NewCodefreshAPIFromConfigMap(ctx context.Context, cs kubernetes.Interface, name, namespace string) (API, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	cm, err := cs.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	opts := ClientOptions{
+		BaseURL:          cm.Data[ConfigMapKeyBaseURL],
+		Token:            cm.Data[ConfigMapKeyToken],
+		APIVersionHeader: cm.Data[ConfigMapKeyAPIVersionHeader],
+		APIVersion:       cm.Data[ConfigMapKeyAPIVersion],
+	}
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("Key %s is missing in ConfigMap %s/%s", ConfigMapKeyBaseURL, namespace, name)
+	}
+	if secretName := cm.Data[ConfigMapKeyTokenSecret]; secretName != "" {
+		key := cm.Data[ConfigMapKeyTokenSecretKey]
+		if key == "" {
+			key = defaultTokenSecretKey
+		}
+		secret, err := cs.CoreV1().Secrets(namespace).Get(secretName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		token, ok := secret.Data[key]
+		if !ok {
+			return nil, fmt.Errorf("Key %s is missing in Secret %s/%s", key, namespace, secretName)
+		}
+		opts.Token = string(token)
+	}
+	if opts.Token == "" {
+		return nil, errors.New("Codefresh token is not set in ConfigMap nor in a referenced Secret")
+	}
+	if v := cm.Data[ConfigMapKeyTimeoutSeconds]; v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %s with error:\n%s", ConfigMapKeyTimeoutSeconds, err)
+		}
+		opts.Timeout = time.Duration(seconds) * time.Second
+	}
+	if v := cm.Data[ConfigMapKeyAsyncMode]; v != "" {
+		async, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %s with error:\n%s", ConfigMapKeyAsyncMode, err)
+		}
+		opts.AsyncMode = async
+	}
+	return NewCodefreshAPI(opts), nil
+}
@@ -0,0 +1,52 @@
+package codefresh
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	debugBodyLimit         = 1024
+	redactedAuthorization  = "[REDACTED]"
+	requestIDHeader        = "X-Request-ID"
+	authorizationHeaderKey = "Authorization"
+)
+
+// debugTransport logs every request to Codefresh and its response at debug level
+// through the logger of the request context,
+// the value of the authorization header is never logged
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := log.Fields{
+		"method": req.Method,
+		"url":    req.URL.String(),
+	}
+	if req.Header.Get(authorizationHeaderKey) != "" {
+		fields["authorization"] = redactedAuthorization
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		loggerFrom(req.Context()).WithFields(fields).WithError(err).Debug("Codefresh API request failed")
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(body) > debugBodyLimit {
+		body = body[:debugBodyLimit]
+	}
+	fields["status"] = res.StatusCode
+	fields["request_id"] = res.Header.Get(requestIDHeader)
+	fields["body"] = string(body)
+	loggerFrom(req.Context()).WithFields(fields).Debug("Codefresh API request")
+	return res, nil
+}
@@ -0,0 +1,47 @@
+package codefresh
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDebugTransportLogsThroughTheLoggerOfTheContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(requestIDHeader, "request-1")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	global := &bytes.Buffer{}
+	out := log.StandardLogger().Out
+	log.SetOutput(global)
+	defer log.SetOutput(out)
+	buffer := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = buffer
+	logger.Level = log.DebugLevel
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "secret-token", DebugHTTP: true})
+
+	_, err := api.List(WithLogger(context.Background(), logger.WithField("context", "ctx-1")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := buffer.String()
+	for _, expected := range []string{"Codefresh API request", "context=ctx-1", "request_id=request-1", "authorization=\"[REDACTED]\""} {
+		if !strings.Contains(lines, expected) {
+			t.Errorf("expected %q in the log of the context, got %s", expected, lines)
+		}
+	}
+	if strings.Contains(lines, "secret-token") {
+		t.Errorf("the token must not be logged, got %s", lines)
+	}
+	if global.Len() != 0 {
+		t.Errorf("expected nothing in the global log, got %s", global.String())
+	}
+}
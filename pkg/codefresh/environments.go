@@ -0,0 +1,64 @@
+package codefresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type (
+	// EnvironmentOptions describes a Codefresh environment bound to a namespace of a registered cluster
+	EnvironmentOptions struct {
+		Name        string
+		ClusterName string
+		Namespace   string
+	}
+
+	environmentPayload struct {
+		Metadata environmentMetadata `json:"metadata"`
+		Spec     environmentSpec     `json:"spec"`
+	}
+
+	environmentMetadata struct {
+		Name string `json:"name"`
+	}
+
+	environmentSpec struct {
+		Type     string               `json:"type"`
+		Clusters []environmentCluster `json:"clusters"`
+	}
+
+	environmentCluster struct {
+		Name       string   `json:"name"`
+		Namespaces []string `json:"namespaces"`
+	}
+)
+
+// CreateEnvironment creates a Kubernetes environment, ErrConflict is returned when it already exists
+func (api *codefreshAPI) CreateEnvironment(ctx context.Context, opt EnvironmentOptions) error {
+	body, status, err := api.do(ctx, "POST", "api/environments-v2", &environmentPayload{
+		Metadata: environmentMetadata{
+			Name: opt.Name,
+		},
+		Spec: environmentSpec{
+			Type: "kubernetes",
+			Clusters: []environmentCluster{
+				{
+					Name:       opt.ClusterName,
+					Namespaces: []string{opt.Namespace},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if status == 409 {
+		return ErrConflict
+	}
+	if status != 200 && status != 201 {
+		err := errors.New(string(body))
+		return fmt.Errorf("Failed to create environment %s", err)
+	}
+	return nil
+}
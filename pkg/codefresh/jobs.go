@@ -0,0 +1,97 @@
+package codefresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+
+	jobPollInterval   = 2 * time.Second
+	defaultJobTimeout = 5 * time.Minute
+)
+
+type (
+	jobResponse struct {
+		JobID string `json:"jobId"`
+	}
+
+	jobStatusResponse struct {
+		Status string          `json:"status"`
+		Error  string          `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+)
+
+// JobID returns the id of the job from the response of Create in async mode,
+// empty when the response is the created cluster itself
+func JobID(body []byte) string {
+	job := &jobResponse{}
+	err := json.Unmarshal(body, job)
+	if err != nil {
+		return ""
+	}
+	return job.JobID
+}
+
+// PollJobStatus waits until the job succeeded or failed and returns the result of the job, the created cluster
+func (api *codefreshAPI) PollJobStatus(ctx context.Context, jobID string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		body, status, err := api.do(ctx, "GET", "api/jobs/"+url.PathEscape(jobID), nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("Timed out waiting for job %s", jobID)
+			}
+			return nil, err
+		}
+		if status != 200 {
+			return nil, fmt.Errorf("Failed to get status of job %s %s", jobID, string(body))
+		}
+		job := &jobStatusResponse{}
+		err = json.Unmarshal(body, job)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == JobFailed {
+			return nil, fmt.Errorf("Job %s failed %s", jobID, job.Error)
+		}
+		if job.Status == JobSucceeded {
+			return job.Result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Timed out waiting for job %s", jobID)
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// CreateAndWait creates the cluster and, when the API works in async mode, waits until the creation job is done,
+// the created cluster is returned in both modes. The job is waited for until the deadline of the context,
+// or 5 minutes when it has none.
+func CreateAndWait(ctx context.Context, api API, opt *CreateOptions) ([]byte, error) {
+	body, err := api.Create(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	jobID := JobID(body)
+	if jobID == "" {
+		return body, nil
+	}
+	timeout := defaultJobTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if timeout <= 0 {
+		return nil, errors.New("No time left to wait for the creation job")
+	}
+	return api.PollJobStatus(ctx, jobID, timeout)
+}
@@ -0,0 +1,44 @@
+package codefresh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateOrUpdateReturnsTheJobResult(t *testing.T) {
+	teamsAssigned := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/clusters/local/cluster":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"jobId": "job-1"}`))
+		case "/api/jobs/job-1":
+			w.Write([]byte(`{"status": "succeeded", "result": {"_id": "cluster-id", "selector": "cluster"}}`))
+		case "/api/clusters/local/cluster/cluster-id/teams":
+			teamsAssigned = "cluster-id"
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "token", AsyncMode: true})
+
+	result, err := CreateOrUpdate(context.Background(), api, &CreateOptions{
+		Name:           "cluster",
+		BehindFirewall: true,
+		TeamNames:      []string{"team"},
+	}, false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := ClusterID(result); id != "cluster-id" {
+		t.Errorf("expected the id of the created cluster, got %q from %s", id, result)
+	}
+	if teamsAssigned != "cluster-id" {
+		t.Error("expected the cluster to be assigned to the teams once the job succeeded")
+	}
+}
@@ -0,0 +1,23 @@
+package codefresh
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx that carries the logger, the client logs
+// the requests made with that ctx through it instead of the global logger
+func WithLogger(ctx context.Context, logger *log.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger carried by ctx, or the global logger when there is none
+func loggerFrom(ctx context.Context) *log.Entry {
+	if logger, ok := ctx.Value(loggerKey{}).(*log.Entry); ok && logger != nil {
+		return logger
+	}
+	return log.NewEntry(log.StandardLogger())
+}
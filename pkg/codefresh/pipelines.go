@@ -0,0 +1,77 @@
+package codefresh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+type (
+	// PipelineOptions describes a pipeline to be created for a cluster
+	PipelineOptions struct {
+		// Spec is a Codefresh pipeline YAML, {{.ClusterName}} is substituted with ClusterName as a quoted YAML string,
+		// so it is used as a whole value, e.g. cluster: {{.ClusterName}}
+		Spec        []byte
+		ClusterName string
+	}
+
+	// pipelineTemplateData is what the spec is rendered with, the values are quoted
+	pipelineTemplateData struct {
+		ClusterName string
+	}
+
+	pipelineResponse struct {
+		Metadata struct {
+			ID string `json:"id"`
+		} `json:"metadata"`
+	}
+)
+
+// yamlString quotes the value as a YAML double quoted string, which is the same as a JSON string,
+// so a colon, a hash or a line break in it can not change the structure of the spec
+func yamlString(value string) string {
+	quoted := &bytes.Buffer{}
+	encoder := json.NewEncoder(quoted)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(quoted.String(), "\n")
+}
+
+// CreatePipeline creates the pipeline and returns its id
+func (api *codefreshAPI) CreatePipeline(opt PipelineOptions) (string, error) {
+	tmpl, err := template.New(opt.ClusterName).Option("missingkey=error").Parse(string(opt.Spec))
+	if err != nil {
+		return "", err
+	}
+	rendered := &bytes.Buffer{}
+	err = tmpl.Execute(rendered, &pipelineTemplateData{
+		ClusterName: yamlString(opt.ClusterName),
+	})
+	if err != nil {
+		return "", err
+	}
+	spec, err := yaml.YAMLToJSON(rendered.Bytes())
+	if err != nil {
+		return "", err
+	}
+	body, status, err := api.do(context.Background(), "POST", "api/pipelines", json.RawMessage(spec))
+	if err != nil {
+		return "", err
+	}
+	if status != 200 && status != 201 {
+		err := errors.New(string(body))
+		return "", fmt.Errorf("Failed to create pipeline %s", err)
+	}
+	pipeline := &pipelineResponse{}
+	err = json.Unmarshal(body, pipeline)
+	if err != nil {
+		return "", err
+	}
+	return pipeline.Metadata.ID, nil
+}
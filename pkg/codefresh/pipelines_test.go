@@ -0,0 +1,41 @@
+package codefresh
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreatePipelineQuotesClusterName(t *testing.T) {
+	var posted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &posted)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"metadata": {"id": "pipeline-id"}}`))
+	}))
+	defer server.Close()
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "token"})
+	name := "prod: eu # west\ninjected: true"
+
+	id, err := api.CreatePipeline(PipelineOptions{
+		Spec:        []byte("metadata:\n  name: deploy\nspec:\n  cluster: {{.ClusterName}}\n"),
+		ClusterName: name,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "pipeline-id" {
+		t.Errorf("expected the id of the pipeline, got %q", id)
+	}
+	spec, _ := posted["spec"].(map[string]interface{})
+	if spec == nil || spec["cluster"] != name {
+		t.Errorf("expected the cluster name as is, got %v", posted)
+	}
+	if _, ok := posted["injected"]; ok || len(posted) != 2 {
+		t.Errorf("expected the cluster name not to add keys, got %v", posted)
+	}
+}
@@ -0,0 +1,97 @@
+package codefresh
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ClientPool spreads the requests between independent clients, each with its own HTTP connection pool.
+// It implements API, so it can be used wherever a single client is.
+type ClientPool struct {
+	clients []*codefreshAPI
+	next    uint32
+}
+
+// NewClientPool creates size clients with the same options, size below 1 is treated as 1
+func NewClientPool(opts ClientOptions, size int) *ClientPool {
+	if size < 1 {
+		size = 1
+	}
+	limit := newRateLimit(opts.RateLimitLowWatermark)
+	pool := &ClientPool{}
+	for i := 0; i < size; i++ {
+		pool.clients = append(pool.clients, newCodefreshAPI(opts, limit))
+	}
+	return pool
+}
+
+// client returns the clients in round-robin
+func (p *ClientPool) client() *codefreshAPI {
+	n := atomic.AddUint32(&p.next, 1)
+	return p.clients[int(n-1)%len(p.clients)]
+}
+
+func (p *ClientPool) Test(ctx context.Context, payload *requestPayload) error {
+	return p.client().Test(ctx, payload)
+}
+
+func (p *ClientPool) Create(ctx context.Context, opt *CreateOptions) ([]byte, error) {
+	return p.client().Create(ctx, opt)
+}
+
+func (p *ClientPool) PatchCluster(ctx context.Context, opt *CreateOptions) ([]byte, error) {
+	return p.client().PatchCluster(ctx, opt)
+}
+
+func (p *ClientPool) PollJobStatus(ctx context.Context, jobID string, timeout time.Duration) ([]byte, error) {
+	return p.client().PollJobStatus(ctx, jobID, timeout)
+}
+
+func (p *ClientPool) AssignToTeams(ctx context.Context, created []byte, teams []string) error {
+	return p.client().AssignToTeams(ctx, created, teams)
+}
+
+func (p *ClientPool) List(ctx context.Context) ([]Cluster, error) {
+	return p.client().List(ctx)
+}
+
+func (p *ClientPool) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	return p.client().GetCluster(ctx, name)
+}
+
+func (p *ClientPool) Delete(ctx context.Context, name string) error {
+	return p.client().Delete(ctx, name)
+}
+
+func (p *ClientPool) CreatePipeline(opt PipelineOptions) (string, error) {
+	return p.client().CreatePipeline(opt)
+}
+
+func (p *ClientPool) WhoAmI(ctx context.Context) (*AccountInfo, error) {
+	return p.client().WhoAmI(ctx)
+}
+
+func (p *ClientPool) Ping(ctx context.Context) error {
+	return p.client().Ping(ctx)
+}
+
+func (p *ClientPool) GetAgentStatus(ctx context.Context, clusterName string) (*AgentStatus, error) {
+	return p.client().GetAgentStatus(ctx, clusterName)
+}
+
+func (p *ClientPool) GetRunnerStatus(ctx context.Context, clusterName string) (*RunnerStatus, error) {
+	return p.client().GetRunnerStatus(ctx, clusterName)
+}
+
+func (p *ClientPool) ClusterURL(created []byte) string {
+	return p.client().ClusterURL(created)
+}
+
+func (p *ClientPool) CreateEnvironment(ctx context.Context, opt EnvironmentOptions) error {
+	return p.client().CreateEnvironment(ctx, opt)
+}
+
+func (p *ClientPool) RunSmokeTest(ctx context.Context, opt SmokeTestOptions) error {
+	return p.client().RunSmokeTest(ctx, opt)
+}
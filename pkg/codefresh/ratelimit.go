@@ -0,0 +1,71 @@
+package codefresh
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimit tracks the budget Codefresh reports in the X-RateLimit-* headers of its responses,
+// it is shared by the clients of a pool as they use the same token
+type rateLimit struct {
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+	// lowWatermark is the remaining budget below which requests wait for the reset
+	lowWatermark int
+}
+
+func newRateLimit(lowWatermark int) *rateLimit {
+	return &rateLimit{
+		lowWatermark: lowWatermark,
+	}
+}
+
+// update reads the headers of the response, responses without them leave the state as is
+func (r *rateLimit) update(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.known = true
+	r.remaining = remaining
+	// the reset is either a unix timestamp or a number of seconds from now
+	if reset > 1000000000 {
+		r.reset = time.Unix(reset, 0)
+	} else {
+		r.reset = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+}
+
+// wait blocks until the budget is reset when it is low, or until ctx is done,
+// the first response after the reset refreshes the budget
+func (r *rateLimit) wait(ctx context.Context) error {
+	r.mu.Lock()
+	delay := time.Duration(0)
+	if r.known && r.remaining <= r.lowWatermark {
+		delay = time.Until(r.reset)
+	}
+	r.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	loggerFrom(ctx).WithField("delay", delay).Warn("Codefresh API rate limit is almost exhausted, waiting for it to reset")
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
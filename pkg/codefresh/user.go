@@ -0,0 +1,78 @@
+package codefresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnauthenticated is returned when Codefresh rejects the token
+var ErrUnauthenticated = errors.New("Codefresh token is invalid or expired")
+
+type (
+	// AccountInfo identifies the user the token belongs to and its active account
+	AccountInfo struct {
+		UserID      string
+		Username    string
+		AccountID   string
+		AccountName string
+	}
+
+	userResponse struct {
+		ID                string `json:"_id"`
+		UserName          string `json:"userName"`
+		ActiveAccountName string `json:"activeAccountName"`
+		Account           []struct {
+			ID   string `json:"_id"`
+			Name string `json:"name"`
+		} `json:"account"`
+	}
+)
+
+// WhoAmI returns the user and the account the requests are made on behalf of
+func (api *codefreshAPI) WhoAmI(ctx context.Context) (*AccountInfo, error) {
+	body, status, err := api.do(ctx, "GET", "api/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == 401 {
+		return nil, ErrUnauthenticated
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to get user %s", err)
+	}
+	user := &userResponse{}
+	err = json.Unmarshal(body, user)
+	if err != nil {
+		return nil, err
+	}
+	info := &AccountInfo{
+		UserID:      user.ID,
+		Username:    user.UserName,
+		AccountName: user.ActiveAccountName,
+	}
+	for _, account := range user.Account {
+		if account.Name == user.ActiveAccountName {
+			info.AccountID = account.ID
+			break
+		}
+	}
+	return info, nil
+}
+
+// Ping checks that Codefresh is reachable and accepts the token
+func (api *codefreshAPI) Ping(ctx context.Context) error {
+	body, status, err := api.do(ctx, "GET", "api/user", nil)
+	if err != nil {
+		return err
+	}
+	if status == 401 {
+		return ErrUnauthenticated
+	}
+	if status != 200 {
+		return fmt.Errorf("Failed to reach Codefresh %s", errors.New(string(body)))
+	}
+	return nil
+}
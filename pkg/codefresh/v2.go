@@ -0,0 +1,260 @@
+package codefresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Values of ClientOptions.APIVersion
+const (
+	APIVersionV1 = "v1"
+	APIVersionV2 = "v2"
+)
+
+// ErrNotSupportedByV2 is returned by the operations the V2 API has no equivalent of
+var ErrNotSupportedByV2 = errors.New("Operation is not supported by Codefresh V2 API")
+
+type (
+	// v2Client talks to the API of the new Codefresh platform (CSDP), it authenticates with
+	// an Argo CD API token sent as a bearer token and keeps the clusters under api/v2/clusters
+	v2Client struct {
+		api *codefreshAPI
+	}
+
+	v2ClusterPayload struct {
+		Name           string            `json:"name"`
+		Server         string            `json:"server"`
+		CAData         []byte            `json:"caData"`
+		BearerToken    []byte            `json:"bearerToken"`
+		BehindFirewall bool              `json:"behindFirewall"`
+		Labels         map[string]string `json:"labels,omitempty"`
+		Description    string            `json:"description,omitempty"`
+		Namespace      string            `json:"namespace,omitempty"`
+	}
+
+	v2Cluster struct {
+		ID             string `json:"id"`
+		Name           string `json:"name"`
+		Server         string `json:"server"`
+		BehindFirewall bool   `json:"behindFirewall"`
+		CAData         []byte `json:"caData,omitempty"`
+		BearerToken    []byte `json:"bearerToken,omitempty"`
+	}
+)
+
+func newV2Client(opts ClientOptions) *v2Client {
+	api := newCodefreshAPI(opts, newRateLimit(opts.RateLimitLowWatermark))
+	api.authScheme = "Bearer "
+	return &v2Client{
+		api: api,
+	}
+}
+
+func newV2ClusterPayload(opt *CreateOptions) *v2ClusterPayload {
+	return &v2ClusterPayload{
+		Name:           opt.Name,
+		Server:         opt.Host,
+		CAData:         opt.CA,
+		BearerToken:    opt.ServiceAccountToken,
+		BehindFirewall: opt.BehindFirewall,
+		Labels:         opt.Tags,
+		Description:    opt.Description,
+		Namespace:      opt.AgentNamespace,
+	}
+}
+
+// normalizeCluster returns the created or updated cluster of a V2 response in the form of the V1 API,
+// so ClusterID reads its id, the credentials are left out. The body is returned as is when it is not a cluster.
+func normalizeCluster(body []byte) []byte {
+	found := &v2Cluster{}
+	err := json.Unmarshal(body, found)
+	if err != nil || found.ID == "" {
+		return body
+	}
+	cluster := found.toCluster()
+	cluster.ClientCa, cluster.ServiceAccountToken = nil, nil
+	normalized, err := json.Marshal(&cluster)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+func (c *v2Cluster) toCluster() Cluster {
+	return Cluster{
+		ID:                  c.ID,
+		Selector:            c.Name,
+		Host:                c.Server,
+		BehindFirewall:      c.BehindFirewall,
+		ClientCa:            c.CAData,
+		ServiceAccountToken: c.BearerToken,
+	}
+}
+
+// Test validates the cluster credentials, the V2 API takes the same payload as Create
+func (c *v2Client) Test(ctx context.Context, payload *requestPayload) error {
+	body, status, err := c.api.do(ctx, "POST", "api/v2/clusters/validate", &v2ClusterPayload{
+		Name:        payload.Selector,
+		Server:      payload.Host,
+		CAData:      payload.ClientCa,
+		BearerToken: payload.ServiceAccountToken,
+	})
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("Failed to test cluster %s", string(body))
+	}
+	return nil
+}
+
+// Create adds the cluster, the V2 API has no jobs so AsyncMode is ignored
+func (c *v2Client) Create(ctx context.Context, opt *CreateOptions) ([]byte, error) {
+	if !opt.BehindFirewall {
+		err := c.Test(ctx, newRequestPayload(opt))
+		if err != nil {
+			return nil, err
+		}
+	}
+	body, status, err := c.api.do(ctx, "POST", "api/v2/clusters", newV2ClusterPayload(opt))
+	if err != nil {
+		return nil, err
+	}
+	if status == 409 {
+		return nil, ErrConflict
+	}
+	if status != 200 && status != 201 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to create cluster %s", err)
+	}
+	return normalizeCluster(body), nil
+}
+
+func (c *v2Client) PatchCluster(ctx context.Context, opt *CreateOptions) ([]byte, error) {
+	if !opt.BehindFirewall {
+		err := c.Test(ctx, newRequestPayload(opt))
+		if err != nil {
+			return nil, err
+		}
+	}
+	body, status, err := c.api.do(ctx, "PATCH", "api/v2/clusters/"+url.PathEscape(opt.Name), newV2ClusterPayload(opt))
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to update cluster %s", err)
+	}
+	return normalizeCluster(body), nil
+}
+
+// PollJobStatus is never needed as Create of the V2 API does not return a job id
+func (c *v2Client) PollJobStatus(ctx context.Context, jobID string, timeout time.Duration) ([]byte, error) {
+	return nil, ErrNotSupportedByV2
+}
+
+// AssignToTeams is not supported, the V2 API has no teams endpoint for clusters
+func (c *v2Client) AssignToTeams(ctx context.Context, created []byte, teams []string) error {
+	return ErrNotSupportedByV2
+}
+
+func (c *v2Client) List(ctx context.Context) ([]Cluster, error) {
+	body, status, err := c.api.do(ctx, "GET", "api/v2/clusters", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to list clusters %s", err)
+	}
+	found := []v2Cluster{}
+	err = json.Unmarshal(body, &found)
+	if err != nil {
+		return nil, err
+	}
+	clusters := []Cluster{}
+	for i := range found {
+		clusters = append(clusters, found[i].toCluster())
+	}
+	return clusters, nil
+}
+
+func (c *v2Client) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	body, status, err := c.api.do(ctx, "GET", "api/v2/clusters/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		err := errors.New(string(body))
+		return nil, fmt.Errorf("Failed to get cluster %s", err)
+	}
+	found := &v2Cluster{}
+	err = json.Unmarshal(body, found)
+	if err != nil {
+		return nil, err
+	}
+	cluster := found.toCluster()
+	return &cluster, nil
+}
+
+func (c *v2Client) Delete(ctx context.Context, name string) error {
+	body, status, err := c.api.do(ctx, "DELETE", "api/v2/clusters/"+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	if status != 200 && status != 204 {
+		err := errors.New(string(body))
+		return fmt.Errorf("Failed to delete cluster %s", err)
+	}
+	return nil
+}
+
+// CreatePipeline is not supported, pipelines of the V2 platform are Argo Workflows kept in git
+func (c *v2Client) CreatePipeline(opt PipelineOptions) (string, error) {
+	return "", ErrNotSupportedByV2
+}
+
+// WhoAmI is not supported, an Argo CD API token is not a Codefresh user
+func (c *v2Client) WhoAmI(ctx context.Context) (*AccountInfo, error) {
+	return nil, ErrNotSupportedByV2
+}
+
+// Ping lists the clusters, it is the call every V2 token that can add clusters is allowed to make
+func (c *v2Client) Ping(ctx context.Context) error {
+	body, status, err := c.api.do(ctx, "GET", "api/v2/clusters", nil)
+	if err != nil {
+		return err
+	}
+	if status == 401 {
+		return ErrUnauthenticated
+	}
+	if status != 200 {
+		return fmt.Errorf("Failed to reach Codefresh %s", errors.New(string(body)))
+	}
+	return nil
+}
+
+func (c *v2Client) GetAgentStatus(ctx context.Context, clusterName string) (*AgentStatus, error) {
+	return nil, ErrNotSupportedByV2
+}
+
+func (c *v2Client) GetRunnerStatus(ctx context.Context, clusterName string) (*RunnerStatus, error) {
+	return nil, ErrNotSupportedByV2
+}
+
+// ClusterURL is empty, the V2 UI has no link to a single cluster
+func (c *v2Client) ClusterURL(created []byte) string {
+	return ""
+}
+
+func (c *v2Client) CreateEnvironment(ctx context.Context, opt EnvironmentOptions) error {
+	return ErrNotSupportedByV2
+}
+
+func (c *v2Client) RunSmokeTest(ctx context.Context, opt SmokeTestOptions) error {
+	return ErrNotSupportedByV2
+}
@@ -0,0 +1,60 @@
+package codefresh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestV2CreateReturnsTheClusterID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/api/v2/clusters" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "cluster-id", "name": "cluster", "server": "https://host", "bearerToken": "dG9rZW4="}`))
+	}))
+	defer server.Close()
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "token", APIVersion: APIVersionV2})
+
+	created, err := api.Create(context.Background(), &CreateOptions{Name: "cluster", Host: "https://host", BehindFirewall: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := ClusterID(created); id != "cluster-id" {
+		t.Errorf("expected the id of the cluster, got %q from %s", id, created)
+	}
+	if strings.Contains(string(created), "dG9rZW4=") {
+		t.Errorf("expected the token to be left out, got %s", created)
+	}
+}
+
+func TestV2PingUsesTheV2API(t *testing.T) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+		if req.Header.Get("authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	api := NewCodefreshAPI(ClientOptions{BaseURL: server.URL + "/", Token: "token", APIVersion: APIVersionV2})
+
+	err := api.Ping(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "/api/v2/clusters" {
+		t.Errorf("expected the V2 API to be called, got %v", paths)
+	}
+	if _, err := api.WhoAmI(context.Background()); err != ErrNotSupportedByV2 {
+		t.Errorf("expected WhoAmI not to be supported, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+)
+
+// GoCheckAgentStatus reports the state of the agent of every cluster registered in Codefresh,
+// clusters whose agent is not healthy or could not be checked are reported as unhealthy
+func (kube *kubernetes) GoCheckAgentStatus(ctx context.Context) error {
+	clusters, err := kube.codefresh.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		logger := log.WithField("name", cluster.Selector)
+		logger.Info("Fetching agent status from Codefresh")
+		agent, err := kube.codefresh.GetAgentStatus(ctx, cluster.Selector)
+		if err != nil {
+			message := fmt.Sprintf("Failed to get agent status with error:\n%s", err)
+			logger.Error(message)
+			kube.reporter.AddToReport(cluster.Selector, reporter.UNHEALTHY, message)
+			continue
+		}
+		entry := reporter.ReportEntry{
+			Name:   cluster.Selector,
+			Status: reporter.HEALTHY,
+			Meta: map[string]string{
+				"agent_version":  agent.Version,
+				"agent_status":   agent.Status,
+				"last_heartbeat": agent.LastHeartbeat.Format(time.RFC3339),
+			},
+		}
+		if agent.Status != codefresh.AgentStatusHealthy {
+			entry.Status = reporter.UNHEALTHY
+			entry.Message = fmt.Sprintf("Agent is %s", agent.Status)
+		}
+		kube.reporter.AddEntry(entry)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clientcmd/api/latest"
+)
+
+// Annotations written by WithKubeconfigAnnotations
+const (
+	AnnotationRegisteredAt = "stevedore.codefresh.io/registered-at"
+	AnnotationClusterID    = "stevedore.codefresh.io/cluster-id"
+)
+
+// AnnotationsExtensionName is the kubeconfig context extension the annotations are kept in,
+// kubeconfig contexts have no annotations of their own:
+//
+//	contexts:
+//	- name: <context-name>
+//	  context:
+//	    extensions:
+//	    - name: stevedore.codefresh.io/annotations
+//	      extension:
+//	        stevedore.codefresh.io/registered-at: <timestamp>
+//	        stevedore.codefresh.io/cluster-id: <id>
+const AnnotationsExtensionName = "stevedore.codefresh.io/annotations"
+
+var registerExtensionConversion sync.Once
+
+// extensionConversionErr is the error of registering the conversion, the conversion of the extensions
+// of client-go passes the extension itself instead of a pointer to the interface, so without it
+// every kubeconfig having extensions fails to be written
+var extensionConversionErr error
+
+func registerUnknownExtensionConversion() error {
+	registerExtensionConversion.Do(func() {
+		extensionConversionErr = latest.Scheme.AddConversionFuncs(
+			func(in *runtime.Unknown, out *runtime.RawExtension, s conversion.Scope) error {
+				out.Raw = in.Raw
+				return nil
+			},
+		)
+	})
+	return extensionConversionErr
+}
+
+// WithKubeconfigAnnotations writes the registration time and the Codefresh id of each added cluster
+// back to the contexts of the kubeconfig file once GoOverAllContexts is done, see AnnotateKubeconfig
+func WithKubeconfigAnnotations(configPath string) Option {
+	return func(kube *kubernetes) {
+		kube.annotateConfigPath = configPath
+	}
+}
+
+// AnnotateKubeconfig merges the annotations into the ones of the contexts of the kubeconfig file,
+// the outer map is keyed by context name. The original file is copied to <configPath>.bak before it is written.
+func AnnotateKubeconfig(configPath string, annotations map[string]map[string]string) error {
+	err := registerUnknownExtensionConversion()
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return err
+	}
+	for contextName, values := range annotations {
+		c, ok := config.Contexts[contextName]
+		if !ok {
+			log.WithField("context_name", contextName).Warn("Context not found in kubeconfig, not annotating it")
+			continue
+		}
+		err = annotateContext(c, values)
+		if err != nil {
+			return fmt.Errorf("Failed to annotate context %s with error:\n%s", contextName, err)
+		}
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(configPath+".bak", data, info.Mode())
+	if err != nil {
+		return fmt.Errorf("Failed to back up kubeconfig with error:\n%s", err)
+	}
+	return clientcmd.WriteToFile(*config, configPath)
+}
+
+func annotateContext(c *api.Context, values map[string]string) error {
+	merged := map[string]string{}
+	if unknown, ok := c.Extensions[AnnotationsExtensionName].(*runtime.Unknown); ok && len(unknown.Raw) > 0 {
+		err := json.Unmarshal(unknown.Raw, &merged)
+		if err != nil {
+			return err
+		}
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	if c.Extensions == nil {
+		c.Extensions = map[string]runtime.Object{}
+	}
+	c.Extensions[AnnotationsExtensionName] = &runtime.Unknown{Raw: raw}
+	return nil
+}
+
+// annotationsData maps the contexts that were added to Codefresh to their annotations
+func annotationsData(entries []reporter.ReportEntry) map[string]map[string]string {
+	data := map[string]map[string]string{}
+	for _, entry := range entries {
+		if !reporter.OnlySucceeded(entry) || entry.Meta["registered_at"] == "" {
+			continue
+		}
+		values := map[string]string{
+			AnnotationRegisteredAt: entry.Meta["registered_at"],
+		}
+		if id := entry.Meta["cluster_id"]; id != "" {
+			values[AnnotationClusterID] = id
+		}
+		data[entry.Name] = values
+	}
+	return data
+}
+
+// saveAnnotations annotates the kubeconfig, failing to write it does not fail the run
+func (kube *kubernetes) saveAnnotations() {
+	if kube.annotateConfigPath == "" {
+		return
+	}
+	data := annotationsData(kube.reporter.Entries())
+	if len(data) == 0 {
+		return
+	}
+	err := AnnotateKubeconfig(kube.annotateConfigPath, data)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to annotate kubeconfig with error:\n%s", err))
+		return
+	}
+	log.WithField("contexts", len(data)).Info("Annotated kubeconfig")
+}
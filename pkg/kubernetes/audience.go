@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// audienceClaim is the aud claim of a JWT, it is either a single string or a list of them
+	audienceClaim []string
+
+	tokenClaims struct {
+		Audience audienceClaim `json:"aud"`
+	}
+)
+
+func (a *audienceClaim) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = audienceClaim{single}
+		return nil
+	}
+	var list []string
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return err
+	}
+	*a = audienceClaim(list)
+	return nil
+}
+
+// WithTokenAudience checks that the service account tokens are valid for the audience of the API server,
+// a mismatch is logged, or fails the context when strict is set. Empty audience disables the check.
+func WithTokenAudience(audience string, strict bool) Option {
+	return func(kube *kubernetes) {
+		kube.tokenAudience = audience
+		kube.strictAudience = strict
+	}
+}
+
+// tokenAudiences decodes the aud claim of the JWT without verifying its signature
+func tokenAudiences(token []byte) ([]string, error) {
+	parts := strings.Split(string(token), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("Token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, err
+	}
+	claims := &tokenClaims{}
+	err = json.Unmarshal(payload, claims)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Audience, nil
+}
+
+// checkTokenAudience returns an error only in strict mode, tokens without the aud claim are the legacy
+// secret based tokens that are accepted by any API server
+func checkTokenAudience(token []byte, options *getOverContextOptions) error {
+	if options.tokenAudience == "" {
+		return nil
+	}
+	audiences, err := tokenAudiences(token)
+	if err != nil {
+		options.logger.Warn(fmt.Sprintf("Failed to decode service account token with error:\n%s", err))
+		return nil
+	}
+	if len(audiences) == 0 {
+		return nil
+	}
+	for _, aud := range audiences {
+		if aud == options.tokenAudience {
+			return nil
+		}
+	}
+	message := fmt.Sprintf("Service account token audience %s does not include %s, Codefresh will fail to authenticate with it", strings.Join(audiences, ","), options.tokenAudience)
+	if options.strictAudience {
+		options.logger.Error(message)
+		return &validationError{message: message}
+	}
+	options.logger.Warn(message)
+	return nil
+}
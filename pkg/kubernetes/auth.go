@@ -0,0 +1,93 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ErrNoAuthInfo is returned when a kubeconfig user carries no credentials at all
+var ErrNoAuthInfo = errors.New("No supported credentials found in kubeconfig user")
+
+// authTypes lists the credential kinds configured on the user,
+// in the order client-go gives them precedence
+func authTypes(authInfo api.AuthInfo) []string {
+	types := []string{}
+	if authInfo.Token != "" || authInfo.TokenFile != "" {
+		types = append(types, "token")
+	}
+	if authInfo.Username != "" || authInfo.Password != "" {
+		types = append(types, "basic")
+	}
+	if authInfo.Exec != nil {
+		types = append(types, "exec")
+	}
+	if authInfo.AuthProvider != nil {
+		types = append(types, "auth-provider")
+	}
+	if authInfo.ClientCertificate != "" || len(authInfo.ClientCertificateData) > 0 ||
+		authInfo.ClientKey != "" || len(authInfo.ClientKeyData) > 0 {
+		types = append(types, "client-certificate")
+	}
+	return types
+}
+
+// ValidateAuthInfo checks that exactly one kind of credentials is set on the kubeconfig user.
+// Multiple kinds are allowed by client-go but usually are a mistake that ends with "Unauthorized"
+// from the API server, so it is only logged.
+func ValidateAuthInfo(authInfo api.AuthInfo) error {
+	types := authTypes(authInfo)
+	if len(types) == 0 {
+		return ErrNoAuthInfo
+	}
+	if len(types) > 1 {
+		log.WithFields(log.Fields{
+			"auth_types": strings.Join(types, ","),
+		}).Warn(fmt.Sprintf("Multiple credentials are set on kubeconfig user, %s takes precedence over %s", types[0], strings.Join(types[1:], ",")))
+	}
+	return nil
+}
+
+// Auth types counted by CountAuthTypes
+const (
+	AuthTypeToken        = "token"
+	AuthTypeCert         = "cert"
+	AuthTypeExec         = "exec"
+	AuthTypeOIDC         = "oidc"
+	AuthTypeAuthProvider = "auth-provider"
+	AuthTypeBasic        = "basic"
+	AuthTypeNone         = "none"
+)
+
+// primaryAuthType is the kind of credentials client-go authenticates the user with
+func primaryAuthType(authInfo *api.AuthInfo) string {
+	if authInfo == nil {
+		return AuthTypeNone
+	}
+	switch types := authTypes(*authInfo); {
+	case len(types) == 0:
+		return AuthTypeNone
+	case types[0] == "client-certificate":
+		return AuthTypeCert
+	case types[0] == "auth-provider" && authInfo.AuthProvider.Name == "oidc":
+		return AuthTypeOIDC
+	default:
+		return types[0]
+	}
+}
+
+// CountAuthTypes counts the contexts by the kind of credentials of their user,
+// contexts pointing to a missing user are counted as none
+func CountAuthTypes(contexts map[string]*api.Context, users map[string]*api.AuthInfo) map[string]int {
+	counts := map[string]int{}
+	for _, c := range contexts {
+		if c == nil {
+			continue
+		}
+		counts[primaryAuthType(users[c.AuthInfo])]++
+	}
+	return counts
+}
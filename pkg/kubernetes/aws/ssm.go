@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	awssign "github.com/codefresh-io/stevedore/pkg/aws"
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/kubernetes"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The AWS SDK is not vendored, the single SSM call is signed by pkg/aws
+const (
+	ssmService = "ssm"
+	ssmTarget  = "AmazonSSM.GetParameter"
+	ssmAmzJSON = "application/x-amz-json-1.1"
+)
+
+type (
+	getParameterRequest struct {
+		Name           string `json:"Name"`
+		WithDecryption bool   `json:"WithDecryption"`
+	}
+
+	getParameterResponse struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+
+	errorResponse struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+)
+
+// NewKubernetesAPIFromSSM reads the kubeconfig from the SecureString (or String) SSM parameter, decrypted,
+// and creates the API from it. The credentials are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and the optional AWS_SESSION_TOKEN, empty awsRegion falls back to AWS_REGION.
+func NewKubernetesAPIFromSSM(ctx context.Context, parameterName string, awsRegion string, cf codefresh.API, r reporter.Reporter, opts ...kubernetes.Option) (kubernetes.API, error) {
+	if awsRegion == "" {
+		awsRegion = os.Getenv("AWS_REGION")
+	}
+	if awsRegion == "" {
+		return nil, errors.New("AWS region is not set")
+	}
+	creds, err := awssign.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	value, err := getParameter(ctx, http.DefaultClient, creds, awsRegion, parameterName)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.Load([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse kubeconfig of SSM parameter %s with error:\n%s", parameterName, err)
+	}
+	return kubernetes.NewKubernetesAPIFromConfig(config, cf, r, opts...), nil
+}
+
+func getParameter(ctx context.Context, client *http.Client, creds *awssign.Credentials, region string, name string) (string, error) {
+	payload, _ := json.Marshal(&getParameterRequest{
+		Name:           name,
+		WithDecryption: true,
+	})
+	host := fmt.Sprintf("ssm.%s.amazonaws.com", region)
+	req, err := http.NewRequest("POST", "https://"+host+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", ssmAmzJSON)
+	req.Header.Set("X-Amz-Target", ssmTarget)
+	awssign.Sign(req, payload, creds, region, ssmService, time.Now())
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		e := &errorResponse{}
+		json.Unmarshal(body, e)
+		return "", fmt.Errorf("Failed to get SSM parameter %s, status %d %s %s", name, res.StatusCode, e.Type, e.Message)
+	}
+	parameter := &getParameterResponse{}
+	err = json.Unmarshal(body, parameter)
+	if err != nil {
+		return "", err
+	}
+	return parameter.Parameter.Value, nil
+}
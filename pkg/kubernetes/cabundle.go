@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+
+	"k8s.io/client-go/rest"
+)
+
+// WithGlobalTLSCABundle trusts the CAs of the PEM file in addition to the CA of each cluster,
+// e.g. for a TLS intercepting proxy in front of all the API servers. The bundle is used only to connect
+// to the clusters, Codefresh is given the CA of the cluster as is. Insecure hosts are not affected.
+func WithGlobalTLSCABundle(path string) Option {
+	return func(kube *kubernetes) {
+		kube.globalTLSCABundle = path
+	}
+}
+
+// withCABundle returns a copy of the config that trusts the CAs of the bundle too,
+// the config itself is returned when there is no bundle or the TLS verification is off
+func withCABundle(config *rest.Config, bundle string) (*rest.Config, error) {
+	if bundle == "" || config.Insecure {
+		return config, nil
+	}
+	extra, err := ioutil.ReadFile(bundle)
+	if err != nil {
+		return nil, err
+	}
+	ca := config.CAData
+	if len(ca) == 0 && config.CAFile != "" {
+		ca, err = ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conn := rest.CopyConfig(config)
+	conn.CAFile = ""
+	conn.CAData = append(append(append([]byte{}, ca...), '\n'), extra...)
+	return conn, nil
+}
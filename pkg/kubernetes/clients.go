@@ -0,0 +1,25 @@
+package kubernetes
+
+import (
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ClientFactory creates the clientset of a context from its config, e.g. to return a fake clientset in tests
+type ClientFactory func(config *rest.Config) (kubeConfig.Interface, error)
+
+// WithKubernetesClientFactory replaces kubernetes.NewForConfig of client-go for the clusters of the contexts,
+// nil keeps it
+func WithKubernetesClientFactory(factory ClientFactory) Option {
+	return func(kube *kubernetes) {
+		kube.clientFactory = factory
+	}
+}
+
+// newClientset uses the factory when set
+func newClientset(factory ClientFactory, config *rest.Config) (kubeConfig.Interface, error) {
+	if factory != nil {
+		return factory(config)
+	}
+	return kubeConfig.NewForConfig(config)
+}
@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+)
+
+type clusterInfoOptions struct {
+	namespace      string
+	name           string
+	descriptionKey string
+}
+
+// WithClusterInfo reads the ConfigMap namespace/name from each cluster and saves its data as Codefresh tags,
+// the value of descriptionKey becomes the description of the cluster instead of a tag
+func WithClusterInfo(namespace string, name string, descriptionKey string) Option {
+	return func(kube *kubernetes) {
+		kube.clusterInfo = &clusterInfoOptions{
+			namespace:      namespace,
+			name:           name,
+			descriptionKey: descriptionKey,
+		}
+	}
+}
+
+// readClusterInfo returns the tags and the description of the cluster,
+// a missing ConfigMap is not an error as the metadata is optional
+func readClusterInfo(clientset kubeConfig.Interface, options *getOverContextOptions) (map[string]string, string) {
+	info := options.clusterInfo
+	logger := options.logger.WithFields(log.Fields{
+		"configmap":           info.name,
+		"configmap_namespace": info.namespace,
+	})
+	logger.Info("Fetching cluster info from cluster")
+	cm, e := clientset.CoreV1().ConfigMaps(info.namespace).Get(info.name, metav1.GetOptions{})
+	if e != nil {
+		logger.Warn(fmt.Sprintf("Failed to get cluster info with error:\n%s", e))
+		return nil, ""
+	}
+	tags := map[string]string{}
+	description := ""
+	for k, v := range cm.Data {
+		if k == info.descriptionKey {
+			description = v
+			continue
+		}
+		tags[k] = v
+	}
+	return tags, description
+}
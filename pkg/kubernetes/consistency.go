@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// WithCAConsistencyCheck warns about hosts the contexts reach with different CAs before GoOverAllContexts starts,
+// which usually means stale credentials or the traffic being intercepted
+func WithCAConsistencyCheck(check bool) Option {
+	return func(kube *kubernetes) {
+		kube.checkCAConsistency = check
+	}
+}
+
+// clusterCA returns the CA of the cluster, read from the file when the data is not inlined
+func clusterCA(cluster *api.Cluster) []byte {
+	if len(cluster.CertificateAuthorityData) > 0 {
+		return cluster.CertificateAuthorityData
+	}
+	if cluster.CertificateAuthority == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(cluster.CertificateAuthority)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// InconsistentCAs maps each host reached by contexts with different CAs to the names of those contexts
+func InconsistentCAs(config *api.Config) map[string][]string {
+	type contextCA struct {
+		name string
+		ca   []byte
+	}
+	byHost := map[string][]contextCA{}
+	for name, c := range config.Contexts {
+		cluster, ok := config.Clusters[c.Cluster]
+		if !ok {
+			continue
+		}
+		byHost[cluster.Server] = append(byHost[cluster.Server], contextCA{name, bytes.TrimSpace(clusterCA(cluster))})
+	}
+	result := map[string][]string{}
+	for host, contexts := range byHost {
+		divergent := false
+		for _, c := range contexts[1:] {
+			if !bytes.Equal(c.ca, contexts[0].ca) {
+				divergent = true
+				break
+			}
+		}
+		if !divergent {
+			continue
+		}
+		names := []string{}
+		for _, c := range contexts {
+			names = append(names, c.name)
+		}
+		sort.Strings(names)
+		result[host] = names
+	}
+	return result
+}
+
+func (kube *kubernetes) warnInconsistentCAs(config *api.Config) {
+	for host, names := range InconsistentCAs(config) {
+		log.WithFields(log.Fields{
+			"host":     host,
+			"contexts": strings.Join(names, ","),
+		}).Warn(fmt.Sprintf("Contexts of host %s use different CAs, check their credentials before adding them", host))
+	}
+}
@@ -0,0 +1,337 @@
+package crd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/kubernetes"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	defaultReconcileInterval = 30 * time.Second
+	// maxRetryBackoff caps the wait before a failed registration is tried again
+	maxRetryBackoff = time.Hour
+)
+
+// CRDReconciler adds the contexts declared by ClusterRegistration objects to Codefresh and deletes them
+// from Codefresh when the objects are deleted. There are no informers in the vendored client-go,
+// so the objects are listed every Interval.
+type CRDReconciler struct {
+	// Client is the REST client of the cluster the objects live in, e.g. clientset.CoreV1().RESTClient()
+	Client rest.Interface
+	// Namespace of the objects, empty means all namespaces
+	Namespace string
+	// KubeConfig holds the contexts the objects refer to
+	KubeConfig *api.Config
+	Codefresh  codefresh.API
+	Interval   time.Duration
+	// Options are the settings accepted by kubernetes.NewKubernetesAPI applied to every registration
+	Options []kubernetes.Option
+}
+
+// Start reconciles the objects in the background until the context is cancelled
+func (r *CRDReconciler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval())
+		defer ticker.Stop()
+		for {
+			err := r.Reconcile(ctx)
+			if err != nil {
+				log.Error(fmt.Sprintf("Failed to reconcile cluster registrations with error:\n%s", err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Reconcile makes a single pass over the objects, an error is returned only when they could not be listed
+func (r *CRDReconciler) Reconcile(ctx context.Context) error {
+	data, err := r.Client.Get().AbsPath(r.path("")...).Context(ctx).DoRaw()
+	if err != nil {
+		return err
+	}
+	list := &ClusterRegistrationList{}
+	err = json.Unmarshal(data, list)
+	if err != nil {
+		return err
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		logger := log.WithFields(log.Fields{
+			"registration": obj.Name,
+			"namespace":    obj.Namespace,
+			"context_name": obj.Spec.Context,
+		})
+		if obj.DeletionTimestamp != nil {
+			r.delete(ctx, obj, logger)
+			continue
+		}
+		if !due(obj, time.Now()) {
+			continue
+		}
+		r.register(ctx, obj, logger)
+	}
+	return nil
+}
+
+// register adds the context to Codefresh and records the result in the status.
+// Once the cluster is registered it is updated on a change of the spec, the names no longer
+// registered, e.g. after the name in the spec changed, are deleted from Codefresh.
+func (r *CRDReconciler) register(ctx context.Context, obj *ClusterRegistration, logger *log.Entry) {
+	if !hasFinalizer(obj) {
+		obj.Finalizers = append(obj.Finalizers, Finalizer)
+		err := r.update(ctx, obj, "")
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to add finalizer with error:\n%s", err))
+			return
+		}
+	}
+	override := clientcmd.ConfigOverrides{}
+	rep := reporter.NewReporter()
+	options := r.Options
+	if len(obj.Status.RegisteredNames) > 0 {
+		options = append(append([]kubernetes.Option{}, r.Options...), kubernetes.WithForceOverwrite(true))
+	}
+	processor := &kubernetes.ContextProcessor{
+		ContextName:    obj.Spec.Context,
+		Name:           obj.clusterName(),
+		Namespace:      valueOrDefault(obj.Spec.Namespace),
+		ServiceAccount: valueOrDefault(obj.Spec.ServiceAccount),
+		BehindFirewall: obj.Spec.BehindFirewall,
+		Config:         clientcmd.NewNonInteractiveClientConfig(*r.KubeConfig, obj.Spec.Context, &override, nil),
+		Codefresh:      r.Codefresh,
+		Reporter:       rep,
+		Logger:         logger,
+		Options:        options,
+	}
+	err := processor.Process(ctx)
+	previous := obj.Status
+	obj.Status = statusOf(obj, rep.Entries(), err)
+	if obj.Status.Phase == PhaseFailed {
+		obj.Status.FailedAttempts = 1
+		if previous.Phase == PhaseFailed && previous.ObservedGeneration == obj.Generation {
+			obj.Status.FailedAttempts = previous.FailedAttempts + 1
+		}
+		retryAfter := metav1.NewTime(time.Now().Add(retryBackoff(r.interval(), obj.Status.FailedAttempts)))
+		obj.Status.RetryAfter = &retryAfter
+		logger.WithField("retry_after", retryAfter.String()).Warn(fmt.Sprintf("Registration failed %d times in a row", obj.Status.FailedAttempts))
+	}
+	if obj.Status.Phase == PhaseRegistered {
+		r.deleteStale(ctx, previous.RegisteredNames, obj.Status.RegisteredNames, logger)
+	} else {
+		// the names are kept until the registration succeeds, so they are still deleted with the object
+		obj.Status.RegisteredNames = mergeNames(previous.RegisteredNames, obj.Status.RegisteredNames)
+	}
+	err = r.update(ctx, obj, "status")
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to update status with error:\n%s", err))
+	}
+}
+
+// delete removes the cluster from Codefresh and then the finalizer, so the object goes away
+func (r *CRDReconciler) delete(ctx context.Context, obj *ClusterRegistration, logger *log.Entry) {
+	if !hasFinalizer(obj) {
+		return
+	}
+	for _, name := range obj.Status.RegisteredNames {
+		logger.WithField("cluster_name", name).Info("Deleting cluster from Codefresh")
+		err := r.Codefresh.Delete(ctx, name)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to delete cluster %s with error:\n%s", name, err))
+			return
+		}
+	}
+	finalizers := []string{}
+	for _, f := range obj.Finalizers {
+		if f != Finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	obj.Finalizers = finalizers
+	err := r.update(ctx, obj, "")
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to remove finalizer with error:\n%s", err))
+	}
+}
+
+// deleteStale deletes the names registered before that are no longer registered
+func (r *CRDReconciler) deleteStale(ctx context.Context, previous []string, current []string, logger *log.Entry) {
+	for _, name := range previous {
+		if containsName(current, name) {
+			continue
+		}
+		logger.WithField("cluster_name", name).Info("Deleting cluster no longer registered from Codefresh")
+		err := r.Codefresh.Delete(ctx, name)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to delete cluster %s with error:\n%s", name, err))
+		}
+	}
+}
+
+// statusOf maps the entries reported by the registration to the status: it is Registered only when every
+// entry is, e.g. DRY_RUN, SMOKE_FAILED or a failed target are not
+func statusOf(obj *ClusterRegistration, entries []reporter.ReportEntry, err error) ClusterRegistrationStatus {
+	status := ClusterRegistrationStatus{
+		ObservedGeneration: obj.Generation,
+	}
+	for _, entry := range entries {
+		if existsInCodefresh(entry.Status) {
+			name := obj.clusterName()
+			if entry.Meta != nil && entry.Meta["codefresh_name"] != "" {
+				name = entry.Meta["codefresh_name"]
+			}
+			status.RegisteredNames = append(status.RegisteredNames, name)
+		}
+	}
+	if err != nil {
+		status.Phase = PhaseFailed
+		status.Message = err.Error()
+		return status
+	}
+	if len(entries) == 0 {
+		status.Phase = PhaseFailed
+		status.Message = "Nothing was reported for the context"
+		return status
+	}
+	status.Phase = PhaseUnchanged
+	messages := []string{}
+	for _, entry := range entries {
+		messages = append(messages, fmt.Sprintf("%s: %s", entry.Name, entry.Status))
+		switch {
+		case entry.Status == reporter.DRY_RUN:
+			if status.Phase != PhaseFailed {
+				status.Phase = PhaseDryRun
+			}
+		case entry.Status == reporter.UNCHANGED:
+		case reporter.IsRegistered(entry.Status):
+			if status.Phase == PhaseUnchanged {
+				status.Phase = PhaseRegistered
+			}
+		default:
+			status.Phase = PhaseFailed
+		}
+	}
+	status.Message = strings.Join(messages, ", ")
+	return status
+}
+
+// existsInCodefresh are the statuses the cluster is saved in Codefresh with, even when the registration failed
+func existsInCodefresh(status string) bool {
+	return reporter.IsRegistered(status) || status == reporter.UNCHANGED || status == reporter.SMOKE_FAILED
+}
+
+func mergeNames(previous []string, current []string) []string {
+	names := append([]string{}, previous...)
+	for _, name := range current {
+		if !containsName(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// due returns true when the object is to be registered: it is new, its spec changed,
+// or it failed and its backoff is over
+func due(obj *ClusterRegistration, now time.Time) bool {
+	if obj.Status.Phase == "" || obj.Status.ObservedGeneration != obj.Generation {
+		return true
+	}
+	if obj.Status.Phase != PhaseFailed {
+		return false
+	}
+	return obj.Status.RetryAfter == nil || !now.Before(obj.Status.RetryAfter.Time)
+}
+
+// retryBackoff doubles the interval with every failed attempt, up to maxRetryBackoff
+func retryBackoff(interval time.Duration, attempts int32) time.Duration {
+	backoff := interval
+	for i := int32(1); i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+func (r *CRDReconciler) interval() time.Duration {
+	if r.Interval <= 0 {
+		return defaultReconcileInterval
+	}
+	return r.Interval
+}
+
+// update writes the object, or its status subresource when subresource is "status"
+func (r *CRDReconciler) update(ctx context.Context, obj *ClusterRegistration, subresource string) error {
+	obj.APIVersion = Group + "/" + Version
+	obj.Kind = "ClusterRegistration"
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	segments := r.pathOf(obj.Namespace, obj.Name)
+	if subresource != "" {
+		segments = append(segments, subresource)
+	}
+	result, err := r.Client.Put().AbsPath(segments...).Body(data).Context(ctx).DoRaw()
+	if err != nil {
+		return err
+	}
+	// the new resource version is needed by the next update of the same pass
+	return json.Unmarshal(result, obj)
+}
+
+func (r *CRDReconciler) path(name string) []string {
+	return r.pathOf(r.Namespace, name)
+}
+
+func (r *CRDReconciler) pathOf(namespace string, name string) []string {
+	segments := []string{"/apis", Group, Version}
+	if namespace != "" {
+		segments = append(segments, "namespaces", namespace)
+	}
+	segments = append(segments, Resource)
+	if name != "" {
+		segments = append(segments, name)
+	}
+	return segments
+}
+
+func hasFinalizer(obj *ClusterRegistration) bool {
+	for _, f := range obj.Finalizers {
+		if f == Finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func valueOrDefault(value string) string {
+	if value == "" {
+		return "default"
+	}
+	return value
+}
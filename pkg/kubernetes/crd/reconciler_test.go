@@ -0,0 +1,93 @@
+package crd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStatusOf(t *testing.T) {
+	obj := &ClusterRegistration{Spec: ClusterRegistrationSpec{Context: "ctx", Name: "cluster"}}
+	target := func(status string, name string) reporter.ReportEntry {
+		return reporter.ReportEntry{Name: "ctx/" + name, Status: status, Meta: map[string]string{"codefresh_name": "cluster-" + name}}
+	}
+	tests := []struct {
+		name    string
+		entries []reporter.ReportEntry
+		err     error
+		phase   string
+		names   []string
+	}{
+		{"added", []reporter.ReportEntry{{Name: "ctx", Status: reporter.SUCCESS}}, nil, PhaseRegistered, []string{"cluster"}},
+		{"dry run", []reporter.ReportEntry{{Name: "ctx", Status: reporter.DRY_RUN}}, nil, PhaseDryRun, nil},
+		{"unchanged", []reporter.ReportEntry{{Name: "ctx", Status: reporter.UNCHANGED}}, nil, PhaseUnchanged, []string{"cluster"}},
+		{"smoke failed", []reporter.ReportEntry{{Name: "ctx", Status: reporter.SMOKE_FAILED}}, nil, PhaseFailed, []string{"cluster"}},
+		{"error", nil, errors.New("unreachable"), PhaseFailed, nil},
+		{"all targets failed", []reporter.ReportEntry{target(reporter.FAILED, "a"), target(reporter.FAILED_CONFLICT, "b")}, nil, PhaseFailed, nil},
+		{"one target failed", []reporter.ReportEntry{target(reporter.SUCCESS, "a"), target(reporter.FAILED, "b")}, nil, PhaseFailed, []string{"cluster-a"}},
+		{"targets added", []reporter.ReportEntry{target(reporter.SUCCESS, "a"), target(reporter.UNCHANGED, "b")}, nil, PhaseRegistered, []string{"cluster-a", "cluster-b"}},
+	}
+	for _, test := range tests {
+		status := statusOf(obj, test.entries, test.err)
+		if status.Phase != test.phase {
+			t.Errorf("%s: expected phase %s, got %s", test.name, test.phase, status.Phase)
+		}
+		if len(status.RegisteredNames) != len(test.names) {
+			t.Errorf("%s: expected names %v, got %v", test.name, test.names, status.RegisteredNames)
+			continue
+		}
+		for i := range test.names {
+			if status.RegisteredNames[i] != test.names[i] {
+				t.Errorf("%s: expected names %v, got %v", test.name, test.names, status.RegisteredNames)
+			}
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		backoff  time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, maxRetryBackoff},
+	}
+	for _, test := range tests {
+		if backoff := retryBackoff(30*time.Second, test.attempts); backoff != test.backoff {
+			t.Errorf("expected a backoff of %s after %d attempts, got %s", test.backoff, test.attempts, backoff)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now()
+	later := metav1.NewTime(now.Add(time.Minute))
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	object := func(generation int64, status ClusterRegistrationStatus) *ClusterRegistration {
+		obj := &ClusterRegistration{Status: status}
+		obj.Generation = generation
+		return obj
+	}
+	tests := []struct {
+		name string
+		obj  *ClusterRegistration
+		due  bool
+	}{
+		{"new", object(1, ClusterRegistrationStatus{}), true},
+		{"registered", object(1, ClusterRegistrationStatus{Phase: PhaseRegistered, ObservedGeneration: 1}), false},
+		{"spec changed", object(2, ClusterRegistrationStatus{Phase: PhaseRegistered, ObservedGeneration: 1}), true},
+		{"failed in backoff", object(1, ClusterRegistrationStatus{Phase: PhaseFailed, ObservedGeneration: 1, RetryAfter: &later}), false},
+		{"failed after backoff", object(1, ClusterRegistrationStatus{Phase: PhaseFailed, ObservedGeneration: 1, RetryAfter: &earlier}), true},
+		{"failed spec changed", object(2, ClusterRegistrationStatus{Phase: PhaseFailed, ObservedGeneration: 1, RetryAfter: &later}), true},
+	}
+	for _, test := range tests {
+		if due(test.obj, now) != test.due {
+			t.Errorf("%s: expected due %v", test.name, test.due)
+		}
+	}
+}
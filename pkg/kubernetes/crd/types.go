@@ -0,0 +1,72 @@
+package crd
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Group, version and resource of the ClusterRegistration CRD, see clusterregistration.yaml
+const (
+	Group    = "stevedore.codefresh.io"
+	Version  = "v1alpha1"
+	Resource = "clusterregistrations"
+
+	// Finalizer keeps the object until the cluster is deleted from Codefresh
+	Finalizer = "stevedore.codefresh.io/cleanup"
+)
+
+// Phases of the registration reported in the status
+const (
+	PhaseRegistered = "Registered"
+	PhaseUnchanged  = "Unchanged"
+	PhaseDryRun     = "DryRun"
+	PhaseFailed     = "Failed"
+)
+
+type (
+	// ClusterRegistration declares a context of the kubeconfig of the operator to be added to Codefresh
+	ClusterRegistration struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   ClusterRegistrationSpec   `json:"spec"`
+		Status ClusterRegistrationStatus `json:"status,omitempty"`
+	}
+
+	// ClusterRegistrationSpec holds the same parameters as the create command with --context
+	ClusterRegistrationSpec struct {
+		Context        string `json:"context"`
+		Name           string `json:"name,omitempty"`
+		Namespace      string `json:"namespace,omitempty"`
+		ServiceAccount string `json:"serviceAccount,omitempty"`
+		BehindFirewall bool   `json:"behindFirewall,omitempty"`
+	}
+
+	// ClusterRegistrationStatus is the result of the last registration
+	ClusterRegistrationStatus struct {
+		Phase   string `json:"phase,omitempty"`
+		Message string `json:"message,omitempty"`
+		// ObservedGeneration is the generation of the spec the result is of
+		ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+		// RegisteredNames are the names the cluster is saved under in Codefresh, they are deleted with the object
+		RegisteredNames []string `json:"registeredNames,omitempty"`
+		// FailedAttempts counts the failed registrations of the spec in a row, the next one is made after RetryAfter
+		FailedAttempts int32        `json:"failedAttempts,omitempty"`
+		RetryAfter     *metav1.Time `json:"retryAfter,omitempty"`
+	}
+
+	// ClusterRegistrationList is returned by the list of the objects
+	ClusterRegistrationList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []ClusterRegistration `json:"items"`
+	}
+)
+
+// clusterName is the name the cluster is saved under in Codefresh
+func (c *ClusterRegistration) clusterName() string {
+	if c.Spec.Name != "" {
+		return c.Spec.Name
+	}
+	return c.Spec.Context
+}
@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type (
+	// ExtractOptions describes the context the credentials of the cluster are extracted for
+	ExtractOptions struct {
+		ContextName string
+		// Config is the client config the clientset was created with
+		Config *rest.Config
+		// Namespace and ServiceAccount are the service account Codefresh should authenticate as
+		Namespace      string
+		ServiceAccount string
+		Logger         *log.Entry
+	}
+
+	// CredentialExtractor returns the token and the CA Codefresh authenticates to the cluster with,
+	// e.g. from a service account secret, a TokenRequest or a vault
+	CredentialExtractor interface {
+		Extract(ctx context.Context, clientset kubeConfig.Interface, opts ExtractOptions) (token []byte, ca []byte, err error)
+	}
+
+	// serviceAccountExtractor reads the token of the service account from its secret, or requests one
+	// when the service account has no secret. The service account is created when it is missing and
+	// WithAutoCreateServiceAccount is set.
+	serviceAccountExtractor struct {
+		options *getOverContextOptions
+	}
+)
+
+// WithCredentialExtractor replaces the default extraction of the token and the CA from the service account,
+// nil keeps it
+func WithCredentialExtractor(extractor CredentialExtractor) Option {
+	return func(kube *kubernetes) {
+		kube.credentialExtractor = extractor
+	}
+}
+
+// credentialExtractor returns the extractor set by WithCredentialExtractor or the default one
+func credentialExtractor(options *getOverContextOptions) CredentialExtractor {
+	if options.credentialExtractor != nil {
+		return options.credentialExtractor
+	}
+	return &serviceAccountExtractor{options: options}
+}
+
+func (x *serviceAccountExtractor) Extract(ctx context.Context, clientset kubeConfig.Interface, opts ExtractOptions) ([]byte, []byte, error) {
+	options := x.options
+	opts.Logger.Info("Fetching service account from cluster")
+	sa, e := clientset.CoreV1().ServiceAccounts(opts.Namespace).Get(opts.ServiceAccount, metav1.GetOptions{})
+	if apierrors.IsNotFound(e) && options.autoCreateSA {
+		sa, e = createServiceAccount(clientset, options)
+	}
+	if e != nil {
+		message := fmt.Sprintf("Failed to get service account token with error:\n%s", e)
+		opts.Logger.Warn(message)
+		return nil, nil, e
+	}
+	if sa == nil {
+		message := fmt.Sprintf("Service account: %s not found in namespace: %s", opts.ServiceAccount, opts.Namespace)
+		opts.Logger.Warn(message)
+		return nil, nil, categorize(reporter.CategoryNoServiceAccount, errors.New(message))
+	}
+	if len(sa.Secrets) == 0 {
+		opts.Logger.Info("Service account has no secret configured, requesting a token")
+		return tokenFromRequest(clientset, opts.Config, sa, options)
+	}
+	return tokenFromSecret(clientset, sa, options)
+}
@@ -0,0 +1,35 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+)
+
+// RunDeadlineExceededMessage is reported for the contexts that were not started before the run deadline
+const RunDeadlineExceededMessage = "Run deadline exceeded"
+
+// WithRunDeadline bounds the total time of GoOverAllContexts, contexts that were not started
+// before the deadline are reported as skipped. The contexts in flight are cancelled when cancelInFlight
+// is set, otherwise they are let to finish under their own timeout.
+func WithRunDeadline(timeout time.Duration, cancelInFlight bool) Option {
+	return func(kube *kubernetes) {
+		kube.runTimeout = timeout
+		kube.cancelInFlight = cancelInFlight
+	}
+}
+
+// runContext starts the deadline of the whole run
+func (kube *kubernetes) runContext() (context.Context, context.CancelFunc) {
+	if kube.runTimeout > 0 {
+		return context.WithTimeout(context.Background(), kube.runTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// contextParent is the context a single kubeconfig context is processed under
+func (kube *kubernetes) contextParent(runCtx context.Context) context.Context {
+	if kube.cancelInFlight {
+		return runCtx
+	}
+	return context.Background()
+}
@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+type contextState struct {
+	RunID          string `json:"runId"`
+	ContextName    string `json:"contextName"`
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"serviceAccount"`
+	Host           string `json:"host"`
+	TokenLength    int    `json:"tokenLength"`
+	CALength       int    `json:"caLength"`
+	BehindFirewall bool   `json:"behindFirewall"`
+	Error          string `json:"error,omitempty"`
+}
+
+// WithDebugMode writes the state of each context to stevedore-debug-<run-id>-<context>.json once it is done
+func WithDebugMode(debug bool) Option {
+	return func(kube *kubernetes) {
+		kube.debugMode = debug
+	}
+}
+
+// WithRunID overrides the generated id of the run
+func WithRunID(id string) Option {
+	return func(kube *kubernetes) {
+		kube.runID = id
+	}
+}
+
+// NewRunID generates a random id of the run, WithRunID overrides the id generated by NewKubernetesAPI
+func NewRunID() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// DumpContextState writes a snapshot of the context processing state to w,
+// the token and the CA are never written, only their length
+func DumpContextState(contextName string, options *getOverContextOptions, result error, w io.Writer) error {
+	state := &contextState{
+		RunID:          options.runID,
+		ContextName:    contextName,
+		Name:           options.name,
+		Namespace:      options.namespace,
+		ServiceAccount: options.serviceaccount,
+		Host:           options.host,
+		TokenLength:    len(options.token),
+		CALength:       len(options.ca),
+		BehindFirewall: options.behindFirewall,
+	}
+	if result != nil {
+		state.Error = result.Error()
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
+
+func (kube *kubernetes) dumpContextState(options *getOverContextOptions, result error) {
+	path := fmt.Sprintf("stevedore-debug-%s-%s.json", kube.runID, unsafeFileNameChars.ReplaceAllString(options.contextName, "_"))
+	file, err := os.Create(path)
+	if err != nil {
+		options.logger.Warn(fmt.Sprintf("Failed to create debug file with error:\n%s", err))
+		return
+	}
+	defer file.Close()
+	err = DumpContextState(options.contextName, options, result, file)
+	if err != nil {
+		options.logger.Warn(fmt.Sprintf("Failed to write debug file with error:\n%s", err))
+	}
+}
@@ -0,0 +1,50 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// WithDryRun goes over the contexts up to the point the clusters would be added to Codefresh:
+// the clients are created and the service account and its token are read, then the context is reported as DRY_RUN.
+// Nothing is changed in Codefresh or in the clusters, the service accounts are not created even with WithAutoCreateServiceAccount.
+func WithDryRun(dryRun bool) Option {
+	return func(kube *kubernetes) {
+		kube.dryRun = dryRun
+	}
+}
+
+// reportDryRun reports what would have been added to Codefresh in place of adding it,
+// once per target when there are registration targets
+func reportDryRun(options *getOverContextOptions, createOptions *codefresh.CreateOptions) {
+	options.meta["host"] = createOptions.Host
+	options.meta["namespace"] = options.namespace
+	options.meta["serviceaccount"] = options.serviceaccount
+	if len(options.targets) > 0 {
+		for _, target := range options.targets {
+			targetCreateOptions, entryName, meta := targetOptions(options, createOptions, target)
+			message := fmt.Sprintf("Would add cluster %s of %s with the token of service account %s/%s", targetCreateOptions.Name, targetCreateOptions.Host, options.namespace, options.serviceaccount)
+			options.logger.WithField("scope", target.Scope).Info(message)
+			options.reporter.AddEntry(reporter.ReportEntry{
+				Name:    entryName,
+				Status:  reporter.DRY_RUN,
+				Message: message,
+				Meta:    meta,
+			})
+		}
+		return
+	}
+	message := fmt.Sprintf("Would add cluster %s of %s with the token of service account %s/%s", createOptions.Name, createOptions.Host, options.namespace, options.serviceaccount)
+	if options.renameFrom != "" && options.renameFrom != options.name {
+		message = fmt.Sprintf("%s, deleting cluster %s", message, options.renameFrom)
+	}
+	options.logger.WithField("behind_firewall", createOptions.BehindFirewall).Info(message)
+	options.reporter.AddEntry(reporter.ReportEntry{
+		Name:    options.contextName,
+		Status:  reporter.DRY_RUN,
+		Message: message,
+		Meta:    options.meta,
+	})
+}
@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// EnvConfig configures the environments created by RegisterNamespacesAsEnvironments
+type EnvConfig struct {
+	// ClusterName is the name of the cluster in Codefresh, default is the context name
+	ClusterName string
+	// NamePrefix is prepended to the namespace to get the name of the environment
+	NamePrefix string
+}
+
+// RegisterNamespacesAsEnvironments creates a Codefresh environment for each namespace of the context matching the selector,
+// the cluster must be added to Codefresh already. Each namespace is reported as <context>/<namespace>.
+func (kube *kubernetes) RegisterNamespacesAsEnvironments(ctx context.Context, contextName string, namespaceSelector labels.Selector, cfEnvConfig EnvConfig) error {
+	override, err := getDefaultOverride()
+	if err != nil {
+		return err
+	}
+	config, err := clientcmd.NewNonInteractiveClientConfig(*kube.getConfig(), contextName, &override, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	config, err = withCABundle(config, kube.globalTLSCABundle)
+	if err != nil {
+		return err
+	}
+	clientset, err := newClientset(kube.clientFactory, config)
+	if err != nil {
+		return err
+	}
+	namespaces, err := clientset.CoreV1().Namespaces().List(metav1.ListOptions{
+		LabelSelector: namespaceSelector.String(),
+	})
+	if err != nil {
+		return err
+	}
+	clusterName := cfEnvConfig.ClusterName
+	if clusterName == "" {
+		clusterName = contextName
+	}
+	for _, ns := range namespaces.Items {
+		name := cfEnvConfig.NamePrefix + ns.Name
+		entryName := fmt.Sprintf("%s/%s", contextName, ns.Name)
+		logger := log.WithFields(log.Fields{
+			"context_name": contextName,
+			"namespace":    ns.Name,
+			"environment":  name,
+		})
+		logger.Info("Creating environment in Codefresh")
+		err := kube.codefresh.CreateEnvironment(ctx, codefresh.EnvironmentOptions{
+			Name:        name,
+			ClusterName: clusterName,
+			Namespace:   ns.Name,
+		})
+		if err == codefresh.ErrConflict {
+			logger.Error(err.Error())
+			kube.reporter.AddToReport(entryName, reporter.FAILED_CONFLICT, err.Error())
+			continue
+		}
+		if err != nil {
+			message := fmt.Sprintf("Failed to create environment with error:\n%s", err)
+			logger.Error(message)
+			kube.reporter.AddToReport(entryName, reporter.FAILED, message)
+			continue
+		}
+		kube.reporter.AddToReport(entryName, reporter.SUCCESS, "")
+	}
+	return nil
+}
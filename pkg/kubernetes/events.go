@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/reference"
+)
+
+// Reasons of the events recorded for each context
+const (
+	EventReasonRegistered         = "ClusterRegistered"
+	EventReasonRegistrationFailed = "ClusterRegistrationFailed"
+)
+
+type (
+	// EventRecorder records Kubernetes events, it is the subset of record.EventRecorder of client-go used by stevedore,
+	// so a recorder of client-go can be passed as is
+	EventRecorder interface {
+		Event(object runtime.Object, eventtype, reason, message string)
+	}
+
+	eventRecorder struct {
+		clientset kubeConfig.Interface
+		component string
+	}
+
+	// statusRecorder keeps the statuses the context is reported with, so the event tells an added cluster
+	// from an unchanged one or a dry run
+	statusRecorder struct {
+		reporter.Reporter
+		statuses []string
+	}
+)
+
+func (r *statusRecorder) AddEntry(entry reporter.ReportEntry) {
+	r.statuses = append(r.statuses, entry.Status)
+	r.Reporter.AddEntry(entry)
+}
+
+// NewEventRecorder creates the events directly with the clientset, without the broadcaster of client-go.
+// Events of a namespace are created in the namespace itself, of other cluster scoped objects in the default namespace.
+func NewEventRecorder(clientset kubeConfig.Interface, component string) EventRecorder {
+	return &eventRecorder{
+		clientset: clientset,
+		component: component,
+	}
+}
+
+func (r *eventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	ref, err := reference.GetReference(scheme.Scheme, object)
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to get reference of event object with error:\n%s", err))
+		return
+	}
+	namespace := ref.Namespace
+	if ref.Kind == "Namespace" {
+		namespace = ref.Name
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	now := metav1.NewTime(time.Now())
+	_, err = r.clientset.CoreV1().Events(namespace).Create(&v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s.", ref.Name),
+			Namespace:    namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventtype,
+		Source: v1.EventSource{
+			Component: r.component,
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	})
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to record event with error:\n%s", err))
+	}
+}
+
+// WithEventRecorder records an event on object after each context, Normal when the cluster was added or updated
+// and Warning when it failed. Skipped and unchanged contexts and dry runs are not recorded.
+func WithEventRecorder(recorder EventRecorder, object runtime.Object) Option {
+	return func(kube *kubernetes) {
+		kube.events = recorder
+		kube.eventObject = object
+	}
+}
+
+// recordEvent is called with the statuses the context was reported with and its final error
+func (kube *kubernetes) recordEvent(options *getOverContextOptions, statuses []string, err error) {
+	if _, ok := err.(*skippedError); ok {
+		return
+	}
+	if err != nil {
+		kube.events.Event(kube.eventObject, v1.EventTypeWarning, EventReasonRegistrationFailed, fmt.Sprintf("Failed to add context %s to Codefresh: %s", options.contextName, err))
+		return
+	}
+	registered := false
+	for _, status := range statuses {
+		registered = registered || reporter.IsRegistered(status)
+	}
+	if !registered {
+		return
+	}
+	kube.events.Event(kube.eventObject, v1.EventTypeNormal, EventReasonRegistered, fmt.Sprintf("Context %s is added to Codefresh as %s", options.contextName, options.name))
+}
@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeEventRecorder records the reasons of the events
+type fakeEventRecorder struct {
+	reasons []string
+}
+
+func (r *fakeEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.reasons = append(r.reasons, reason)
+}
+
+func TestEventRecordedOnlyForAddedClusters(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		reasons []string
+	}{
+		{"added", nil, []string{EventReasonRegistered}},
+		{"dry run", []Option{WithDryRun(true)}, nil},
+		{"failed", []Option{WithCredentialExtractor(&fakeExtractor{fail: map[string]bool{"ctx-0": true}})}, []string{EventReasonRegistrationFailed}},
+	}
+	for _, test := range tests {
+		events := &fakeEventRecorder{}
+		opts := append([]Option{
+			WithCredentialExtractor(&fakeExtractor{}),
+			WithEventRecorder(events, &v1.Namespace{}),
+		}, test.opts...)
+		kube := NewKubernetesAPIFromConfig(testConfig(1), &fakeCodefresh{}, reporter.NewReporter(), opts...)
+
+		kube.GoOverAllContexts()
+
+		if len(events.reasons) != len(test.reasons) || (len(test.reasons) > 0 && events.reasons[0] != test.reasons[0]) {
+			t.Errorf("%s: expected events %v, got %v", test.name, test.reasons, events.reasons)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"path"
+)
+
+// WithExcludedContexts never adds the contexts with one of the names or matching one of the glob patterns,
+// e.g. docker-desktop or minikube*. Exclusion is checked before any other filter and wins over them.
+func WithExcludedContexts(names []string, patterns []string) Option {
+	return func(kube *kubernetes) {
+		kube.excludeContexts = names
+		kube.excludeContextPatterns = patterns
+	}
+}
+
+func (kube *kubernetes) isExcluded(contextName string) bool {
+	for _, name := range kube.excludeContexts {
+		if name == contextName {
+			return true
+		}
+	}
+	for _, pattern := range kube.excludeContextPatterns {
+		if ok, _ := path.Match(pattern, contextName); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package kubernetes
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ExportAsKubeconfig builds a kubeconfig with a context per cluster registered in Codefresh,
+// each context is named after the cluster and uses its service account token
+func (kube *kubernetes) ExportAsKubeconfig(ctx context.Context) (*api.Config, error) {
+	clusters, err := kube.codefresh.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := api.NewConfig()
+	for _, c := range clusters {
+		log.WithField("name", c.Selector).Info("Fetching cluster from Codefresh")
+		cluster, err := kube.codefresh.GetCluster(ctx, c.Selector)
+		if err != nil {
+			return nil, err
+		}
+		name := cluster.Selector
+		config.Clusters[name] = &api.Cluster{
+			Server:                   cluster.Host,
+			CertificateAuthorityData: cluster.ClientCa,
+		}
+		config.AuthInfos[name] = &api.AuthInfo{
+			Token: string(cluster.ServiceAccountToken),
+		}
+		config.Contexts[name] = &api.Context{
+			Cluster:  name,
+			AuthInfo: name,
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+)
+
+// ContextExtensionName is the name of the kubeconfig context extension read by GoOverAllContexts:
+//
+//	contexts:
+//	- name: <context-name>
+//	  context:
+//	    extensions:
+//	    - name: stevedore
+//	      extension:
+//	        provider: eks
+//	        behindFirewall: true
+//	        name: <name in Codefresh>
+//	        namespace: <namespace>
+//	        serviceaccount: <service account>
+const ContextExtensionName = "stevedore"
+
+// ContextExtension holds per context settings kept in the kubeconfig itself,
+// the name and namespace maps take precedence over it
+type ContextExtension struct {
+	// Provider is used instead of detecting it when the provider filters are set
+	Provider       string `json:"provider,omitempty"`
+	BehindFirewall *bool  `json:"behindFirewall,omitempty"`
+	Name           string `json:"name,omitempty"`
+	Namespace      string `json:"namespace,omitempty"`
+	ServiceAccount string `json:"serviceaccount,omitempty"`
+}
+
+// readContextExtension returns nil when the context has no stevedore extension
+func readContextExtension(c *api.Context) (*ContextExtension, error) {
+	if c == nil {
+		return nil, nil
+	}
+	obj, ok := c.Extensions[ContextExtensionName]
+	if !ok || obj == nil {
+		return nil, nil
+	}
+	var raw []byte
+	if unknown, ok := obj.(*runtime.Unknown); ok {
+		raw = unknown.Raw
+	} else {
+		var err error
+		raw, err = json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ext := &ContextExtension{}
+	err := yaml.Unmarshal(raw, ext)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s extension with error:\n%s", ContextExtensionName, err)
+	}
+	return ext, nil
+}
+
+// applyContextExtension overrides the global settings with the ones of the extension
+func applyContextExtension(ext *ContextExtension, options *getOverContextOptions) {
+	if ext.Provider != "" {
+		options.provider = ext.Provider
+	}
+	if ext.BehindFirewall != nil {
+		options.behindFirewall = *ext.BehindFirewall
+	}
+	if ext.Name != "" {
+		options.name = ext.Name
+	}
+	if ext.Namespace != "" {
+		options.namespace = ext.Namespace
+	}
+	if ext.ServiceAccount != "" {
+		options.serviceaccount = ext.ServiceAccount
+		options.serviceaccounts = nil
+	}
+}
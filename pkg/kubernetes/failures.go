@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"context"
+	"net"
+	"net/url"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// categorizedError marks the failures that can not be told apart by the error itself,
+// e.g. a missing token secret is not an API error
+type categorizedError struct {
+	category string
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func categorize(category string, err error) error {
+	return &categorizedError{
+		category: category,
+		err:      err,
+	}
+}
+
+// failureCategory tells why the context failed for the breakdown of the summary,
+// permission errors of the API server take precedence over what was being done when they happened
+func failureCategory(err error) string {
+	cause := err
+	category := ""
+	if ce, ok := err.(*categorizedError); ok {
+		cause, category = ce.err, ce.category
+	}
+	switch {
+	case apierrors.IsForbidden(cause) || apierrors.IsUnauthorized(cause):
+		return reporter.CategoryRBAC
+	case category != "":
+		return category
+	case cause == ErrNoSuitableServiceAccount:
+		return reporter.CategoryNoServiceAccount
+	case apierrors.IsNotFound(cause):
+		return notFoundCategory(cause)
+	case cause == context.DeadlineExceeded || apierrors.IsTimeout(cause) || apierrors.IsServerTimeout(cause):
+		return reporter.CategoryTimeout
+	case cause == codefresh.ErrUnauthenticated || cause == codefresh.ErrConflict:
+		return reporter.CategoryCodefresh
+	}
+	if _, ok := cause.(*url.Error); ok {
+		return reporter.CategoryConnectivity
+	}
+	if _, ok := cause.(net.Error); ok {
+		return reporter.CategoryConnectivity
+	}
+	return reporter.CategoryOther
+}
+
+// notFoundCategory tells the missing service account from the missing secret by the kind of the status
+func notFoundCategory(err error) string {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return reporter.CategoryOther
+	}
+	switch status.Status().Details.Kind {
+	case "serviceaccounts":
+		return reporter.CategoryNoServiceAccount
+	case "secrets":
+		return reporter.CategoryNoSecret
+	}
+	return reporter.CategoryOther
+}
@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// fakeCodefresh records the created clusters, failing for the names in fail,
+// the methods the tests do not set up panic on the nil API
+type fakeCodefresh struct {
+	codefresh.API
+	delay time.Duration
+	fail  map[string]bool
+
+	mu       sync.Mutex
+	created  []string
+	teams    map[string][]string
+	inFlight int
+	maxSeen  int
+}
+
+func (f *fakeCodefresh) Create(ctx context.Context, opt *codefresh.CreateOptions) ([]byte, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxSeen {
+		f.maxSeen = f.inFlight
+	}
+	f.mu.Unlock()
+	time.Sleep(f.delay)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	if f.fail[opt.Name] {
+		return nil, errors.New("failed on purpose")
+	}
+	f.created = append(f.created, opt.Name)
+	return []byte(fmt.Sprintf(`{"_id": "%s"}`, opt.Name)), nil
+}
+
+func (f *fakeCodefresh) AssignToTeams(ctx context.Context, created []byte, teams []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.teams == nil {
+		f.teams = map[string][]string{}
+	}
+	f.teams[codefresh.ClusterID(created)] = teams
+	return nil
+}
+
+func (f *fakeCodefresh) ClusterURL([]byte) string {
+	return ""
+}
+
+// fakeExtractor returns a token without calling the cluster, failing for the contexts in fail
+type fakeExtractor struct {
+	fail map[string]bool
+}
+
+func (x *fakeExtractor) Extract(ctx context.Context, clientset kubeConfig.Interface, opts ExtractOptions) ([]byte, []byte, error) {
+	if x.fail[opts.ContextName] {
+		return nil, nil, errors.New("failed on purpose")
+	}
+	return []byte("token"), []byte("ca"), nil
+}
+
+// testConfig returns a kubeconfig of n contexts named ctx-<i>, the server is never called by the fake extractor
+func testConfig(n int) *api.Config {
+	config := api.NewConfig()
+	config.Clusters["cluster"] = &api.Cluster{Server: "https://127.0.0.1:1"}
+	config.AuthInfos["user"] = &api.AuthInfo{Token: "token"}
+	for i := 0; i < n; i++ {
+		config.Contexts[fmt.Sprintf("ctx-%d", i)] = &api.Context{Cluster: "cluster", AuthInfo: "user"}
+	}
+	return config
+}
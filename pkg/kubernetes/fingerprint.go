@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// tokenRenewalFraction is the part of the lifetime of a requested token left when the cluster is registered again
+const tokenRenewalFraction = 5
+
+type (
+	// FingerprintStore persists a fingerprint of each registered cluster,
+	// so unchanged clusters are not written to Codefresh again
+	FingerprintStore struct {
+		path         string
+		mu           sync.Mutex
+		fingerprints map[string]fingerprintRecord
+	}
+
+	// fingerprintRecord is the fingerprint of a cluster, with the expiry of its token when the token was requested
+	fingerprintRecord struct {
+		Fingerprint    string     `json:"fingerprint"`
+		RegisteredAt   *time.Time `json:"registeredAt,omitempty"`
+		TokenExpiresAt *time.Time `json:"tokenExpiresAt,omitempty"`
+	}
+)
+
+// UnmarshalJSON reads the plain fingerprints of the state files written before the token expiry was recorded
+func (r *fingerprintRecord) UnmarshalJSON(data []byte) error {
+	var plain string
+	if json.Unmarshal(data, &plain) == nil {
+		r.Fingerprint = plain
+		return nil
+	}
+	type record fingerprintRecord
+	return json.Unmarshal(data, (*record)(r))
+}
+
+// expiring returns true when the token expires within a fifth of its lifetime
+func (r fingerprintRecord) expiring(now time.Time) bool {
+	if r.TokenExpiresAt == nil {
+		return false
+	}
+	margin := time.Duration(0)
+	if r.RegisteredAt != nil {
+		margin = r.TokenExpiresAt.Sub(*r.RegisteredAt) / tokenRenewalFraction
+	}
+	return !now.Add(margin).Before(*r.TokenExpiresAt)
+}
+
+// LoadFingerprintStore reads the state file, a missing file is an empty store
+func LoadFingerprintStore(path string) (*FingerprintStore, error) {
+	store := &FingerprintStore{
+		path:         path,
+		fingerprints: map[string]fingerprintRecord{},
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &store.fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Unchanged returns true when the cluster was registered with the same fingerprint
+// and its token is not about to expire
+func (s *FingerprintStore) Unchanged(name string, fingerprint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.fingerprints[name]
+	return ok && record.Fingerprint == fingerprint && !record.expiring(time.Now())
+}
+
+// Save records the fingerprint of the cluster and writes the state file,
+// tokenExpiresAt is the expiry of a requested token and zero for the tokens that do not expire
+func (s *FingerprintStore) Save(name string, fingerprint string, tokenExpiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := fingerprintRecord{Fingerprint: fingerprint}
+	if !tokenExpiresAt.IsZero() {
+		now := time.Now().UTC()
+		expiresAt := tokenExpiresAt.UTC()
+		record.RegisteredAt, record.TokenExpiresAt = &now, &expiresAt
+	}
+	s.fingerprints[name] = record
+	data, err := json.MarshalIndent(s.fingerprints, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, data, 0600)
+}
+
+// WithFingerprintStore skips clusters whose fingerprint did not change since they were registered.
+// A token requested with the TokenRequest API is new on every run so it is not part of the fingerprint,
+// its expiry is stored instead and the cluster is registered again once a fifth of the lifetime of the token is left.
+func WithFingerprintStore(store *FingerprintStore) Option {
+	return func(kube *kubernetes) {
+		kube.fingerprints = store
+	}
+}
+
+// verified returns true for the statuses of clusters that passed all the checks after they were added,
+// only their fingerprints are saved
+func verified(status string) bool {
+	return status == reporter.SUCCESS || status == reporter.REFRESHED || status == reporter.VERIFIED ||
+		status == reporter.RUNNER_CONNECTED
+}
+
+// fingerprint hashes everything that is sent to Codefresh when the cluster is created,
+// except the token when it was requested
+func fingerprint(opt *codefresh.CreateOptions, requestedToken bool) string {
+	if requestedToken {
+		withoutToken := *opt
+		withoutToken.ServiceAccountToken = nil
+		opt = &withoutToken
+	}
+	data, _ := json.Marshal(opt)
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
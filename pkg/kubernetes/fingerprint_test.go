@@ -0,0 +1,172 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestFingerprintIgnoresRequestedToken(t *testing.T) {
+	first := &codefresh.CreateOptions{Name: "cluster", Host: "https://host", ServiceAccountToken: []byte("first")}
+	second := &codefresh.CreateOptions{Name: "cluster", Host: "https://host", ServiceAccountToken: []byte("second")}
+
+	if fingerprint(first, true) != fingerprint(second, true) {
+		t.Error("expected a requested token not to change the fingerprint")
+	}
+	if fingerprint(first, false) == fingerprint(second, false) {
+		t.Error("expected the token of a secret to change the fingerprint")
+	}
+	if string(first.ServiceAccountToken) != "first" {
+		t.Error("expected the options to be left as they are")
+	}
+}
+
+func tempStore(t *testing.T, content string) (*FingerprintStore, func()) {
+	dir, err := ioutil.TempDir("", "fingerprints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "state.json")
+	if content != "" {
+		err = ioutil.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := LoadFingerprintStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestFingerprintStoreReadsPlainFingerprints(t *testing.T) {
+	store, cleanup := tempStore(t, `{"cluster": "abc"}`)
+	defer cleanup()
+
+	if !store.Unchanged("cluster", "abc") || store.Unchanged("cluster", "def") {
+		t.Error("expected the plain fingerprint of the state file to be read")
+	}
+}
+
+func TestFingerprintStoreTokenExpiry(t *testing.T) {
+	store, cleanup := tempStore(t, "")
+	defer cleanup()
+
+	err := store.Save("long-lived", "abc", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save("expired", "abc", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save("secret", "abc", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !store.Unchanged("long-lived", "abc") {
+		t.Error("expected a token far from its expiry to be unchanged")
+	}
+	if store.Unchanged("expired", "abc") {
+		t.Error("expected an expired token to be registered again")
+	}
+	if !store.Unchanged("secret", "abc") {
+		t.Error("expected a token without expiry to be unchanged")
+	}
+}
+
+// tokenRequestConfig returns a kubeconfig of the API server serving a service account without secrets
+// whose requested tokens expire at expiresAt
+func tokenRequestConfig(t *testing.T, expiresAt time.Time) (*api.Config, func()) {
+	sa := &v1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+	}
+	tr := &authv1.TokenRequest{
+		TypeMeta: metav1.TypeMeta{Kind: "TokenRequest", APIVersion: "authentication.k8s.io/v1"},
+		Status: authv1.TokenRequestStatus{
+			Token:               "requested",
+			ExpirationTimestamp: metav1.NewTime(expiresAt),
+		},
+	}
+	server, _ := apiServer(t, map[string]interface{}{
+		"/api/v1/namespaces/default/serviceaccounts/default":       sa,
+		"/api/v1/namespaces/default/serviceaccounts/default/token": tr,
+	})
+	config := api.NewConfig()
+	config.Clusters["cluster"] = &api.Cluster{Server: server.URL}
+	config.AuthInfos["user"] = &api.AuthInfo{Token: "token"}
+	config.Contexts["ctx-0"] = &api.Context{Cluster: "cluster", AuthInfo: "user"}
+	return config, server.Close
+}
+
+func TestExpiredRequestedTokenIsRegisteredAgain(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		status    string
+	}{
+		{"expired", time.Now().Add(-time.Minute), reporter.SUCCESS},
+		{"long-lived", time.Now().Add(time.Hour), reporter.UNCHANGED},
+	}
+	for _, test := range tests {
+		config, closeServer := tokenRequestConfig(t, test.expiresAt)
+		store, cleanup := tempStore(t, "")
+		cf := &fakeCodefresh{}
+		for run := 0; run < 2; run++ {
+			rep := reporter.NewReporter()
+			kube := NewKubernetesAPIFromConfig(config, cf, rep, WithFingerprintStore(store))
+
+			kube.GoOverAllContexts()
+
+			entries := rep.Entries()
+			if run == 1 && (len(entries) != 1 || entries[0].Status != test.status) {
+				t.Errorf("%s: expected the second run to report %s, got %+v", test.name, test.status, entries)
+			}
+		}
+		closeServer()
+		cleanup()
+	}
+}
+
+// smokeCodefresh fails every smoke test
+type smokeCodefresh struct {
+	fakeCodefresh
+}
+
+func (f *smokeCodefresh) RunSmokeTest(ctx context.Context, opt codefresh.SmokeTestOptions) error {
+	return errors.New("failed on purpose")
+}
+
+func TestFingerprintNotSavedWhenSmokeTestFails(t *testing.T) {
+	store, cleanup := tempStore(t, "")
+	defer cleanup()
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(testConfig(1), &smokeCodefresh{}, rep,
+		WithCredentialExtractor(&fakeExtractor{}),
+		WithFingerprintStore(store),
+		WithSmokeTest("smoke", 0),
+	)
+
+	kube.GoOverAllContexts()
+
+	if entries := rep.Entries(); len(entries) != 1 || entries[0].Status != reporter.SMOKE_FAILED {
+		t.Fatalf("expected the smoke test to fail, got %+v", entries)
+	}
+	if _, ok := store.fingerprints["ctx-0"]; ok {
+		t.Error("expected the fingerprint not to be saved, so the next run tests the cluster again")
+	}
+}
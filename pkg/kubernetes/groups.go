@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// sharedClient is the client of a server reused by all the contexts pointing to it
+type sharedClient struct {
+	config    *rest.Config
+	clientset kubeConfig.Interface
+}
+
+// GroupContextsByServer maps the server URL to the sorted names of the contexts pointing to it,
+// contexts of a missing cluster are left out
+func GroupContextsByServer(config *api.Config) map[string][]string {
+	groups := map[string][]string{}
+	for name, c := range config.Contexts {
+		cluster, ok := config.Clusters[c.Cluster]
+		if !ok {
+			continue
+		}
+		groups[cluster.Server] = append(groups[cluster.Server], name)
+	}
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+	return groups
+}
+
+// GoOverContextGroups works like GoOverAllContexts but creates a single client per server, with the credentials
+// of the first context of the server, and reuses it for all the contexts pointing to that server.
+// When the client can not be created each context of the server creates its own.
+func (kube *kubernetes) GoOverContextGroups() {
+	kube.reportStaleMappings("name map", kube.nameMap)
+	kube.reportStaleMappings("namespace map", kube.namespaceMap)
+	rawConfig := kube.getConfig()
+	kube.validateConfig(rawConfig)
+	kube.checkDuplicateNames()
+	runCtx, cancel := kube.runContext()
+	defer cancel()
+	if kube.runPreflight(runCtx) != nil {
+		return
+	}
+	groups := GroupContextsByServer(rawConfig)
+	servers := []string{}
+	for server := range groups {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	kube.shuffle(servers)
+	if progress, ok := kube.reporter.(interface{ SetTotal(int) }); ok {
+		progress.SetTotal(len(rawConfig.Contexts))
+	}
+	kube.runShards(servers, func(worker int, server string) {
+		names := groups[server]
+		shared := kube.newSharedClient(rawConfig, server, names[0])
+		for _, contextName := range names {
+			kube.goOverContextInConfig(runCtx, rawConfig, worker, contextName, shared)
+		}
+	})
+	kube.saveResults()
+	kube.saveAnnotations()
+	authTypes := CountAuthTypes(rawConfig.Contexts, rawConfig.AuthInfos)
+	log.WithFields(log.Fields{
+		"auth_types": authTypes,
+	}).Info("ContextTypeStats")
+	kube.reporter.SetAuthTypes(authTypes)
+}
+
+// newSharedClient returns nil when the client of the context can not be created
+func (kube *kubernetes) newSharedClient(rawConfig *api.Config, server string, contextName string) *sharedClient {
+	logger := log.WithFields(log.Fields{
+		"server":       server,
+		"context_name": contextName,
+	})
+	override, err := getDefaultOverride()
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to create config overrides with error:\n%s", err))
+		return nil
+	}
+	config, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, contextName, &override, nil).ClientConfig()
+	if err != nil {
+		logger.Warn("Failed to create shared client of the server, each context creates its own")
+		return nil
+	}
+	applyServerOverrides(config, kube.serverOverrides, logger)
+	if applyInsecureHosts(config, kube.insecureHosts) {
+		logger.Warn("Skipping TLS verification of the server, it is in the insecure hosts")
+	}
+	config.Timeout = kube.contextTimeout
+	connConfig, err := withCABundle(config, kube.globalTLSCABundle)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to read CA bundle with error:\n%s", err))
+		return nil
+	}
+	applyTransportWrapper(connConfig, kube.transportWrapper)
+	clientset, err := newClientset(kube.clientFactory, connConfig)
+	if err != nil {
+		logger.Warn("Failed to create shared client of the server, each context creates its own")
+		return nil
+	}
+	logger.Info("Created shared client of the server")
+	return &sharedClient{
+		config:    config,
+		clientset: clientset,
+	}
+}
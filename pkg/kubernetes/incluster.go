@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// Default paths the service account of the pod is mounted at
+const (
+	DefaultInClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	DefaultInClusterCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// WithInClusterPaths sets the files the token and the CA of the cluster stevedore runs in are read from
+// when the context can not be used and the cluster is registered with the in-cluster config,
+// e.g. for projected tokens or service meshes mounting them elsewhere. Empty paths keep the defaults.
+func WithInClusterPaths(tokenFile string, caFile string) Option {
+	return func(kube *kubernetes) {
+		kube.inClusterTokenFile = tokenFile
+		kube.inClusterCAFile = caFile
+	}
+}
+
+// inClusterConfig is rest.InClusterConfig with configurable token and CA files
+func inClusterConfig(tokenFile string, caFile string) (*rest.Config, error) {
+	if tokenFile == "" {
+		tokenFile = DefaultInClusterTokenFile
+	}
+	if caFile == "" {
+		caFile = DefaultInClusterCAFile
+	}
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, rest.ErrNotInCluster
+	}
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(caFile); err != nil {
+		return nil, err
+	}
+	return &rest.Config{
+		Host:        "https://" + net.JoinHostPort(host, port),
+		BearerToken: strings.TrimSpace(string(token)),
+		TLSClientConfig: rest.TLSClientConfig{
+			CAFile: caFile,
+		},
+	}, nil
+}
@@ -1,15 +1,25 @@
 package kubernetes
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/codefresh-io/stevedore/pkg/codefresh"
 	"github.com/codefresh-io/stevedore/pkg/reporter"
 	log "github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubeConfig "k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 )
@@ -17,23 +27,156 @@ import (
 type (
 	API interface {
 		GoOverAllContexts()
-		GoOverContextByName(string, string, string, bool, string)
+		GoOverContextGroups()
+		GoOverContextByName(string, string, []string, bool, string)
 		GoOverCurrentContext()
+		GoCreatePipelinesForAllContexts(string) error
+		GoReportOrphanedClusters(bool) error
+		GoDeleteAllClusters(DeleteAllOptions) error
+		ExportAsKubeconfig(context.Context) (*api.Config, error)
+		GoCheckAgentStatus(context.Context) error
+		RegisterNamespacesAsEnvironments(context.Context, string, labels.Selector, EnvConfig) error
+		PrintContextList(io.Writer, string) error
 	}
 
 	kubernetes struct {
+		// config is replaced by ConfigWatcher, use getConfig to read it
 		config    *api.Config
+		configMu  sync.RWMutex
 		codefresh codefresh.API
 		reporter  reporter.Reporter
+		teamNames []string
+
+		storageClassName string
+		reclaimPolicy    string
+		agentNamespace   string
+		overrides        ContextOverrides
+
+		agentServiceAccount string
+
+		contextTimeout time.Duration
+		retries        int
+		retryDelay     time.Duration
+
+		includeProviders []string
+		excludeProviders []string
+
+		logDir      string
+		batchedLogs bool
+
+		nameMap      map[string]string
+		namespaceMap map[string]string
+
+		namespace       string
+		serviceaccounts []string
+
+		contextEnvPrefix string
+
+		tokenExpirySeconds int64
+		tokenAudience      string
+		strictAudience     bool
+
+		autoCreateSA     bool
+		cleanupOnFailure bool
+		saClusterRole    string
+
+		fingerprints *FingerprintStore
+
+		runID     string
+		debugMode bool
+
+		forceOverwrite bool
+		renameFrom     string
+		dryRun         bool
+
+		runTimeout     time.Duration
+		cancelInFlight bool
+
+		workers         int
+		maxConcurrency  int
+		shuffleContexts bool
+		shuffleSeed     int64
+
+		clusterInfo *clusterInfoOptions
+
+		checkCAConsistency bool
+
+		excludeContexts        []string
+		excludeContextPatterns []string
+
+		events      EventRecorder
+		eventObject runtime.Object
+
+		insecureHosts []string
+
+		serverOverrides map[string]string
+
+		smokeTestPipeline string
+		smokeTestTimeout  time.Duration
+
+		runnerCheck        bool
+		runnerCheckTimeout time.Duration
+
+		refreshTokens bool
+
+		writeResultsConfigMap     bool
+		resultsConfigMapNamespace string
+
+		annotateConfigPath string
+
+		namePattern   *regexp.Regexp
+		nameMaxLength int
+
+		timingCache string
+
+		configLoadAttempts int
+		configLoadDelay    time.Duration
+
+		targets []RegistrationTarget
+
+		clientFactory ClientFactory
+
+		preflightCheck PreflightCheck
+
+		credentialExtractor CredentialExtractor
+
+		includeNodeCount bool
+
+		secretFieldSelector fields.Selector
+
+		globalTLSCABundle string
+
+		transportWrapper TransportWrapper
+
+		inClusterTokenFile string
+		inClusterCAFile    string
+		// clashes are found before GoOverAllContexts and GoOverContextGroups start
+		clashes map[string][]string
+
+		strictConfigValidation bool
+		// configProblems are found by the validation phase of GoOverAllContexts and GoOverContextGroups
+		configProblems map[string][]string
 	}
+
+	// Option configures optional behaviour of the kubernetes API
+	Option func(*kubernetes)
 )
 
-func getDefaultOverride() clientcmd.ConfigOverrides {
+// WithTeamNames assigns every added cluster to the given Codefresh teams
+func WithTeamNames(names []string) Option {
+	return func(kube *kubernetes) {
+		kube.teamNames = names
+	}
+}
+
+// getDefaultOverride returns the overrides every context is loaded with, the error is reserved
+// for resolving the default server, none of the current overrides can fail
+func getDefaultOverride() (clientcmd.ConfigOverrides, error) {
 	return clientcmd.ConfigOverrides{
 		ClusterInfo: api.Cluster{
 			Server: "",
 		},
-	}
+	}, nil
 }
 
 type getOverContextOptions struct {
@@ -46,122 +189,498 @@ type getOverContextOptions struct {
 	reporter       reporter.Reporter
 	behindFirewall bool
 	name           string
+	teamNames      []string
+
+	// serviceaccounts are the candidates tried in order, serviceaccount is the first of them
+	serviceaccounts []string
+
+	storageClassName string
+	reclaimPolicy    string
+	agentNamespace   string
+	// agentServiceAccount is the identity of the agent, serviceaccount is only the source of the token
+	agentServiceAccount string
+
+	includeProviders []string
+	excludeProviders []string
+	// provider is set by the context extension, it is detected when empty
+	provider string
+	// meta is reported along with the result of the context
+	meta map[string]string
+
+	tokenExpirySeconds int64
+	tokenAudience      string
+	strictAudience     bool
+	autoCreateSA       bool
+	cleanupOnFailure   bool
+	saClusterRole      string
+
+	fingerprints *FingerprintStore
+
+	runID          string
+	forceOverwrite bool
+	renameFrom     string
+	dryRun         bool
+	clusterInfo    *clusterInfoOptions
+	insecureHosts  []string
+	refreshTokens  bool
+	targets        []RegistrationTarget
+	namePattern    *regexp.Regexp
+	nameMaxLength  int
+	// smokeTestPipeline is run against the added cluster when set
+	smokeTestPipeline string
+	smokeTestTimeout  time.Duration
+	// contextTimeout bounds the context, the timeout of its override takes precedence over WithContextTimeout
+	contextTimeout time.Duration
+	// runnerCheck waits for the runner of a behind firewall cluster to reach it
+	runnerCheck        bool
+	runnerCheckTimeout time.Duration
+	// clientFactory creates the clientset when set, kubernetes.NewForConfig otherwise
+	clientFactory ClientFactory
+	// includeNodeCount adds node_count to the meta
+	includeNodeCount bool
+	// secretFieldSelector is ANDed with the type selector of the token secrets list
+	secretFieldSelector fields.Selector
+	// globalTLSCABundle is trusted in addition to the CA of the cluster
+	globalTLSCABundle string
+	// serverOverrides replace the server of the context when it matches
+	serverOverrides map[string]string
+	// transportWrapper wraps the transport of the client of the context when set
+	transportWrapper TransportWrapper
+	// credentialExtractor extracts the token and the CA, the service account one is used when nil
+	credentialExtractor CredentialExtractor
+	// createdSA, createdRole and createdBinding are set once the objects were created by the default extractor,
+	// so the cleanup on failure deletes only what stevedore created
+	createdSA      bool
+	createdRole    bool
+	createdBinding bool
+	// requestedToken is set when the token was requested with the TokenRequest API instead of read from a secret,
+	// tokenExpiresAt is the expiry of the requested token
+	requestedToken bool
+	tokenExpiresAt time.Time
+	// inClusterTokenFile and inClusterCAFile replace the default mount paths of the in-cluster config
+	inClusterTokenFile string
+	inClusterCAFile    string
+	// shared is the client of the server reused by GoOverContextGroups, the context creates its own when nil
+	shared *sharedClient
+	// host, token and ca are kept for the debug dump
+	host  string
+	token []byte
+	ca    []byte
 }
 
-func goOverContext(options *getOverContextOptions) error {
-	var host string
-	var ca []byte
-	var token []byte
+// secretNamespace returns the namespace of the secret referenced by the service account,
+// the reference namespace is respected when set, otherwise the secret lives next to the service account
+func secretNamespace(saNamespace string, ref v1.ObjectReference) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return saNamespace
+}
+
+// newClient creates the client of the cluster of the context, bounded by the deadline of ctx
+func newClient(ctx context.Context, options *getOverContextOptions) (*rest.Config, kubeConfig.Interface, error) {
 	clientCnf, e := options.config.ClientConfig()
 	if e != nil {
 		message := fmt.Sprintf("Failed to create config with error:\n%s", e)
 		options.logger.Warn(message)
-		clientCnf, e = rest.InClusterConfig()
+		clientCnf, e = inClusterConfig(options.inClusterTokenFile, options.inClusterCAFile)
 		if e != nil {
 			message = fmt.Sprintf("Failed to create in cluster config with error:\n%s", e)
 			options.logger.Warn(message)
-			return e
+			return nil, nil, e
 		}
 	}
 	options.logger.Info("Created config for context")
-	host = clientCnf.Host
+	applyServerOverrides(clientCnf, options.serverOverrides, options.logger)
+	if applyInsecureHosts(clientCnf, options.insecureHosts) {
+		options.logger.Warn("Skipping TLS verification of the server, it is in the insecure hosts")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		clientCnf.Timeout = time.Until(deadline)
+	}
+
+	connCnf, e := withCABundle(clientCnf, options.globalTLSCABundle)
+	if e != nil {
+		message := fmt.Sprintf("Failed to read CA bundle with error:\n%s", e)
+		options.logger.Warn(message)
+		return nil, nil, e
+	}
+
+	applyTransportWrapper(connCnf, options.transportWrapper)
 
 	options.logger.Info("Creating rest client")
-	clientset, e := kubeConfig.NewForConfig(clientCnf)
+	clientset, e := newClientset(options.clientFactory, connCnf)
 	if e != nil {
 		message := fmt.Sprintf("Failed to create kubernetes client with error:\n%s", e)
 		options.logger.Warn(message)
-		
-		return e
+
+		return nil, nil, e
 	}
 	options.logger.Info("Created client set for context")
+	return clientCnf, clientset, nil
+}
 
-	options.logger.Info("Fetching service account from cluster")
-	sa, e := clientset.CoreV1().ServiceAccounts(options.namespace).Get(options.serviceaccount, metav1.GetOptions{})
-	if e != nil {
-		message := fmt.Sprintf("Failed to get service account token with error:\n%s", e)
-		options.logger.Warn(message)
+func goOverContext(ctx context.Context, options *getOverContextOptions) (err error) {
+	var host string
+	var ca []byte
+	var token []byte
+	ctx = codefresh.WithLogger(ctx, options.logger)
+	if e := validateName(options); e != nil {
+		options.logger.Warn(e.Error())
 		return e
 	}
-	if sa == nil {
-		message := fmt.Sprintf("Service account: %s not found in namespace: %s", options.serviceaccount, options.namespace)
-		options.logger.Warn(message)
-		return fmt.Errorf(message)
+	rawConfig, e := options.config.RawConfig()
+	if e == nil {
+		if kubeContext, ok := rawConfig.Contexts[options.contextName]; ok {
+			if authInfo, ok := rawConfig.AuthInfos[kubeContext.AuthInfo]; ok {
+				e = ValidateAuthInfo(*authInfo)
+				if e != nil {
+					message := fmt.Sprintf("Invalid kubeconfig user %s with error:\n%s", kubeContext.AuthInfo, e)
+					options.logger.Warn(message)
+					return e
+				}
+			}
+		}
 	}
-	if len(sa.Secrets) == 0 {
-		message := fmt.Sprintf("Service account has no secrect configured for serviceaccount: %s", options.serviceaccount)
-		options.logger.Warn(message)
-		return fmt.Errorf(message)
+	var clientCnf *rest.Config
+	var clientset kubeConfig.Interface
+	if options.shared != nil {
+		options.logger.Info("Reusing client of the server")
+		clientCnf, clientset = options.shared.config, options.shared.clientset
+	} else {
+		clientCnf, clientset, e = newClient(ctx, options)
+		if e != nil {
+			return e
+		}
+	}
+	host = clientCnf.Host
+	if options.includeNodeCount {
+		countNodes(clientset, options)
 	}
-	secretName := string(sa.Secrets[0].Name)
-	namespace := sa.Namespace
-	options.logger.WithFields(log.Fields{
-		"secret_name": secretName,
-		"namespace":   namespace,
-	}).Info(fmt.Sprint("Found service account accisiated with secret"))
 
-	options.logger.Info("Fetching secret from cluster")
-	secret, e := clientset.CoreV1().Secrets(namespace).Get(secretName, metav1.GetOptions{})
+	if len(options.includeProviders) > 0 || len(options.excludeProviders) > 0 {
+		provider := options.provider
+		if provider == "" {
+			provider = DetectProvider(clientset)
+			options.logger.WithField("provider", provider).Info("Detected cluster provider")
+		}
+		options.meta["provider"] = provider
+		e = checkProvider(provider, options.includeProviders, options.excludeProviders)
+		if e != nil {
+			options.logger.Info(e.Error())
+			return e
+		}
+	}
+
+	var tags map[string]string
+	var description string
+	if options.clusterInfo != nil {
+		tags, description = readClusterInfo(clientset, options)
+	}
+
+	if len(options.serviceaccounts) > 1 {
+		e = selectServiceAccount(clientset, options)
+		if e != nil {
+			return e
+		}
+	}
+	if options.cleanupOnFailure {
+		defer func() {
+			if err != nil && options.createdSA {
+				deleteServiceAccount(clientset, options)
+			}
+		}()
+	}
+	token, ca, e = credentialExtractor(options).Extract(ctx, clientset, ExtractOptions{
+		ContextName:    options.contextName,
+		Config:         clientCnf,
+		Namespace:      options.namespace,
+		ServiceAccount: options.serviceaccount,
+		Logger:         options.logger,
+	})
+	if e != nil {
+		return e
+	}
+	e = checkTokenAudience(token, options)
 	if e != nil {
-		message := fmt.Sprintf("Failed to get secrets with error:\n%s", e)
-		options.logger.Warn(message)
 		return e
 	}
-	token = secret.Data["token"]
-	ca = secret.Data["ca.crt"]
-	options.logger.Info(fmt.Sprint("Found secret"))
+	options.host, options.token, options.ca = host, token, ca
 
+	createOptions := &codefresh.CreateOptions{
+		Host:                host,
+		Name:                options.name,
+		ServiceAccountToken: token,
+		CA:                  ca,
+		BehindFirewall:      options.behindFirewall,
+		TeamNames:           options.teamNames,
+		StorageClassName:    options.storageClassName,
+		ReclaimPolicy:       options.reclaimPolicy,
+		Tags:                tags,
+		Description:         description,
+		AgentNamespace:      options.agentNamespace,
+	}
+	if options.behindFirewall && options.agentNamespace != "" {
+		options.meta["agent_namespace"] = options.agentNamespace
+	}
+	if options.behindFirewall && options.agentServiceAccount != "" {
+		e = checkAgentServiceAccount(clientset, options)
+		if e != nil {
+			return e
+		}
+		createOptions.AgentServiceAccount = options.agentServiceAccount
+		options.meta["agent_serviceaccount"] = options.agentServiceAccount
+	}
+	if options.dryRun {
+		reportDryRun(options, createOptions)
+		return nil
+	}
+	if len(options.targets) > 0 {
+		return registerTargets(ctx, options, createOptions)
+	}
+	fp := fingerprint(createOptions, options.requestedToken)
+	if options.fingerprints != nil && options.fingerprints.Unchanged(options.name, fp) {
+		options.reporter.AddEntry(reporter.ReportEntry{
+			Name:   options.contextName,
+			Status: reporter.UNCHANGED,
+			Meta:   options.meta,
+		})
+		options.logger.Info("Cluster is unchanged since it was added, skipping")
+		return nil
+	}
+
+	if options.renameFrom != "" && options.renameFrom != options.name {
+		e = renameCluster(ctx, options)
+		if e != nil {
+			return categorize(reporter.CategoryCodefresh, e)
+		}
+	}
 	options.logger.Info(fmt.Sprint("Creating cluster in Codefresh"))
-	result, e := options.codefresh.Create(host, options.name, token, ca, options.behindFirewall)
+	result, e := codefresh.CreateOrUpdate(ctx, options.codefresh, createOptions, options.forceOverwrite && !options.refreshTokens)
+	status := reporter.SUCCESS
+	if e == codefresh.ErrConflict && options.refreshTokens {
+		result, status, e = refreshIfStale(ctx, options, createOptions)
+		if e == nil && status == reporter.UNCHANGED {
+			options.reporter.AddEntry(reporter.ReportEntry{
+				Name:   options.contextName,
+				Status: reporter.UNCHANGED,
+				Meta:   options.meta,
+			})
+			return nil
+		}
+	} else if e == codefresh.ErrConflict {
+		options.logger.Error(fmt.Sprintf("Cluster %s already exists in Codefresh, use --overwrite to replace it", options.name))
+		return e
+	}
 	if e != nil {
 		message := fmt.Sprintf("Failed to add cluster with error:\n%s", e)
 		options.logger.Error(message)
-		return e
+		if e == codefresh.ErrConflict {
+			return e
+		}
+		return categorize(reporter.CategoryCodefresh, e)
+	}
+	if url := options.codefresh.ClusterURL(result); url != "" {
+		options.meta["url"] = url
 	}
-	options.reporter.AddToReport(options.contextName, reporter.SUCCESS, string(result))
+	if id := codefresh.ClusterID(result); id != "" {
+		options.meta["cluster_id"] = id
+	}
+	options.meta["registered_at"] = time.Now().UTC().Format(time.RFC3339)
 	options.logger.Info(fmt.Sprint("Cluster added!"))
+	message := string(result)
+	if options.behindFirewall && options.runnerCheck {
+		status, message = runnerCheck(ctx, options)
+	}
+	if options.smokeTestPipeline != "" {
+		status, message = smokeTest(ctx, options)
+	}
+	options.reporter.AddEntry(reporter.ReportEntry{
+		Name:    options.contextName,
+		Status:  status,
+		Message: message,
+		Meta:    options.meta,
+	})
+	// a failed smoke test or a runner that did not connect yet is checked again by the next run
+	if options.fingerprints != nil && verified(status) {
+		e = options.fingerprints.Save(options.name, fp, options.tokenExpiresAt)
+		if e != nil {
+			message := fmt.Sprintf("Failed to save cluster fingerprint with error:\n%s", e)
+			options.logger.Warn(message)
+		}
+	}
 	return nil
 }
 
 func (kube *kubernetes) GoOverAllContexts() {
-	contexts := kube.config.Contexts
+	kube.reportStaleMappings("name map", kube.nameMap)
+	kube.reportStaleMappings("namespace map", kube.namespaceMap)
+	if log.GetLevel() >= log.DebugLevel {
+		kube.logAccount()
+	}
+	rawConfig := kube.getConfig()
+	kube.validateConfig(rawConfig)
+	kube.checkDuplicateNames()
+	if kube.checkCAConsistency {
+		kube.warnInconsistentCAs(rawConfig)
+	}
+	contexts := rawConfig.Contexts
+	runCtx, cancel := kube.runContext()
+	defer cancel()
+	if kube.runPreflight(runCtx) != nil {
+		return
+	}
+	names := []string{}
 	for contextName := range contexts {
-		logger := log.WithFields(log.Fields{
-			"context_name": contextName,
-		})
-		logger.Info("Working on context")
-		logger.Info("Creating config")
-		override := getDefaultOverride()
-		config := clientcmd.NewNonInteractiveClientConfig(*kube.config, contextName, &override, nil)
-		options := &getOverContextOptions{
-			contextName:    contextName,
-			config:         config,
-			logger:         logger,
-			codefresh:      kube.codefresh,
-			reporter:       kube.reporter,
-			behindFirewall: false,
-			name:           contextName,
-		}
-		err := goOverContext(options)
-		if err != nil {
-			kube.reporter.AddToReport(contextName, reporter.FAILED, err.Error())
-			continue
-		}
+		names = append(names, contextName)
+	}
+	sort.Strings(names)
+	kube.shuffle(names)
+	kube.logRunSummary(names)
+	if progress, ok := kube.reporter.(interface{ SetTotal(int) }); ok {
+		progress.SetTotal(len(names))
+	}
+	started := time.Now()
+	kube.runShards(names, func(worker int, contextName string) {
+		// the config is read for every context, so a reload by ConfigWatcher applies to the contexts not started yet
+		kube.goOverContextInConfig(runCtx, kube.getConfig(), worker, contextName, nil)
+	})
+	kube.saveTimings(time.Since(started), len(names))
+	kube.saveResults()
+	kube.saveAnnotations()
+	authTypes := CountAuthTypes(contexts, rawConfig.AuthInfos)
+	log.WithFields(log.Fields{
+		"auth_types": authTypes,
+	}).Info("ContextTypeStats")
+	kube.reporter.SetAuthTypes(authTypes)
+}
+
+// logAccount logs the Codefresh account the clusters are going to be added to
+func (kube *kubernetes) logAccount() {
+	account, err := kube.codefresh.WhoAmI(context.Background())
+	if err == codefresh.ErrUnauthenticated {
+		log.Error(err.Error())
+		return
+	}
+	if err == codefresh.ErrNotSupportedByV2 {
+		log.Debug("Codefresh account is not known to the V2 API")
+		return
+	}
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to get Codefresh account with error:\n%s", err))
+		return
+	}
+	log.WithFields(log.Fields{
+		"user":       account.Username,
+		"account_id": account.AccountID,
+	}).Debug(fmt.Sprintf("Authenticated to Codefresh account %s", account.AccountName))
+}
+
+// goOverContextInConfig processes a single context of GoOverAllContexts, all its lines are logged
+// with the same fields so a single context or worker can be filtered out of a parallel run
+func (kube *kubernetes) goOverContextInConfig(runCtx context.Context, rawConfig *api.Config, worker int, contextName string, shared *sharedClient) {
+	logger, closeLogger := kube.contextLogger(contextName, log.Fields{
+		"context_name": contextName,
+		"worker":       worker,
+		"run_id":       kube.runID,
+	})
+	defer closeLogger()
+	logger.Info("Working on context")
+	logger.Info("Creating config")
+	override, e := getDefaultOverride()
+	if e != nil {
+		message := fmt.Sprintf("Failed to create config overrides with error:\n%s", e)
+		logger.Warn(message)
+		kube.reporter.AddToReport(contextName, reporter.FAILED, message)
+		return
+	}
+	config := clientcmd.NewNonInteractiveClientConfig(*rawConfig, contextName, &override, nil)
+	options := &getOverContextOptions{
+		contextName:    contextName,
+		config:         config,
+		logger:         logger,
+		codefresh:      kube.codefresh,
+		reporter:       kube.reporter,
+		behindFirewall: false,
+		name:           contextName,
+		namespace:      kube.namespace,
+	}
+	options.serviceaccounts = orDefaultServiceAccount(kube.serviceaccounts)
+	options.serviceaccount = options.serviceaccounts[0]
+	options.shared = shared
+	kube.applySettings(options)
+	if kube.isExcluded(contextName) {
+		logger.Info("Context is excluded, skipping")
+		kube.report(options, reporter.SKIPPED_EXCLUDED, "Context is excluded")
+		return
+	}
+	if problems := kube.configProblems[contextName]; kube.strictConfigValidation && len(problems) > 0 {
+		message := fmt.Sprintf("Invalid kubeconfig entries: %s", strings.Join(problems, "; "))
+		logger.Warn(message)
+		kube.report(options, reporter.FAILED, message)
+		return
+	}
+	e = kube.resolveContext(rawConfig, options)
+	if e != nil {
+		logger.Warn(e.Error())
+		kube.report(options, reporter.FAILED, e.Error())
+		return
+	}
+	if clashes := kube.clashes[contextName]; len(clashes) > 0 {
+		message := fmt.Sprintf("Codefresh name %s is planned for contexts %s as well", options.name, strings.Join(clashes, ","))
+		logger.Warn(message)
+		kube.report(options, reporter.FAILED, message)
+		return
+	}
+	if runCtx.Err() != nil {
+		logger.Warn(RunDeadlineExceededMessage)
+		kube.report(options, reporter.SKIPPED, RunDeadlineExceededMessage)
+		return
+	}
+	kube.processContext(kube.contextParent(runCtx), options)
+}
+
+// resolveContext applies the context extension and the name and namespace maps to the options
+func (kube *kubernetes) resolveContext(rawConfig *api.Config, options *getOverContextOptions) error {
+	ext, e := readContextExtension(rawConfig.Contexts[options.contextName])
+	if e != nil {
+		return e
+	}
+	if ext != nil {
+		applyContextExtension(ext, options)
 	}
+	applyContextEnv(kube.contextEnvPrefix, options)
+	if name, ok := kube.nameMap[options.contextName]; ok {
+		options.name = name
+	}
+	if namespace, ok := kube.namespaceMap[options.contextName]; ok {
+		options.namespace = namespace
+	}
+	return nil
 }
 
-func (kube *kubernetes) GoOverContextByName(contextName string, namespace string, serviceaccount string, bf bool, name string) {
+// GoOverContextByName adds the context with the first of the service accounts that exists and has a token secret,
+// default is used when there are none
+func (kube *kubernetes) GoOverContextByName(contextName string, namespace string, serviceaccounts []string, bf bool, name string) {
+	serviceaccounts = orDefaultServiceAccount(serviceaccounts)
 	var override clientcmd.ConfigOverrides
 	var config clientcmd.ClientConfig
-	override = getDefaultOverride()
-	config = clientcmd.NewNonInteractiveClientConfig(*kube.config, contextName, &override, nil)
-	logger := log.WithFields(log.Fields{
+	logger, closeLogger := kube.contextLogger(contextName, log.Fields{
 		"context_name":    contextName,
 		"namespace":       namespace,
-		"serviceaccount":  serviceaccount,
+		"serviceaccount":  strings.Join(serviceaccounts, ","),
 		"behind_firewall": bf,
 		"name":            name,
 	})
+	defer closeLogger()
+	override, e := getDefaultOverride()
+	if e != nil {
+		message := fmt.Sprintf("Failed to create config overrides with error:\n%s", e)
+		logger.Warn(message)
+		kube.reporter.AddToReport(contextName, reporter.FAILED, message)
+		return
+	}
+	config = clientcmd.NewNonInteractiveClientConfig(*kube.getConfig(), contextName, &override, nil)
 	options := &getOverContextOptions{
 		contextName:    contextName,
 		config:         config,
@@ -169,27 +688,35 @@ func (kube *kubernetes) GoOverContextByName(contextName string, namespace string
 		codefresh:      kube.codefresh,
 		reporter:       kube.reporter,
 		namespace:      namespace,
-		serviceaccount: serviceaccount,
+		serviceaccount: serviceaccounts[0],
 		behindFirewall: bf,
 		name:           name,
 	}
-	err := goOverContext(options)
-	if err != nil {
-		kube.reporter.AddToReport(contextName, reporter.FAILED, err.Error())
-	}
+	options.serviceaccounts = serviceaccounts
+	kube.applySettings(options)
+	// only a single context can take over the cluster of the previous name
+	options.renameFrom = kube.renameFrom
+	kube.processContext(context.Background(), options)
 }
 
+// GoOverCurrentContext adds the current context, its namespace and service accounts are resolved the same way as by GoOverAllContexts
 func (kube *kubernetes) GoOverCurrentContext() {
-	override := getDefaultOverride()
-	config := clientcmd.NewDefaultClientConfig(*kube.config, &override)
+	override, err := getDefaultOverride()
+	if err != nil {
+		kube.reporter.AddToReport("current-context", reporter.FAILED, err.Error())
+		return
+	}
+	config := clientcmd.NewDefaultClientConfig(*kube.getConfig(), &override)
 	rawConfig, err := config.RawConfig()
 	if err != nil {
 		kube.reporter.AddToReport("current-context", reporter.FAILED, err.Error())
+		return
 	}
 	contextName := rawConfig.CurrentContext
-	logger := log.WithFields(log.Fields{
+	logger, closeLogger := kube.contextLogger(contextName, log.Fields{
 		"context_name": contextName,
 	})
+	defer closeLogger()
 	options := &getOverContextOptions{
 		contextName:    contextName,
 		config:         config,
@@ -198,17 +725,38 @@ func (kube *kubernetes) GoOverCurrentContext() {
 		reporter:       kube.reporter,
 		behindFirewall: false,
 		name:           contextName,
+		namespace:      kube.namespace,
 	}
-	err = goOverContext(options)
+	options.serviceaccounts = orDefaultServiceAccount(kube.serviceaccounts)
+	options.serviceaccount = options.serviceaccounts[0]
+	kube.applySettings(options)
+	err = kube.resolveContext(&rawConfig, options)
 	if err != nil {
-		kube.reporter.AddToReport(contextName, reporter.FAILED, err.Error())
+		logger.Warn(err.Error())
+		kube.report(options, reporter.FAILED, err.Error())
+		return
 	}
+	kube.processContext(context.Background(), options)
+}
+
+func NewKubernetesAPI(kubeConfigPath string, codefresh codefresh.API, reporter reporter.Reporter, opts ...Option) API {
+	return NewKubernetesAPIFromConfig(clientcmd.GetConfigFromFileOrDie(kubeConfigPath), codefresh, reporter, opts...)
 }
 
-func NewKubernetesAPI(kubeConfigPath string, codefresh codefresh.API, reporter reporter.Reporter) API {
-	return &kubernetes{
-		config:    clientcmd.GetConfigFromFileOrDie(kubeConfigPath),
+// NewKubernetesAPIFromConfig works like NewKubernetesAPI with a kubeconfig that is not read from a file
+func NewKubernetesAPIFromConfig(config *api.Config, codefresh codefresh.API, reporter reporter.Reporter, opts ...Option) API {
+	kube := &kubernetes{
+		config:    config,
 		codefresh: codefresh,
 		reporter:  reporter,
+		runID:     NewRunID(),
+
+		namespace:       "default",
+		serviceaccounts: []string{"default"},
+		maxConcurrency:  DefaultMaxConcurrency,
+	}
+	for _, opt := range opts {
+		opt(kube)
 	}
+	return kube
 }
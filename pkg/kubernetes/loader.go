@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	defaultConfigLoadAttempts = 3
+	defaultConfigLoadDelay    = time.Second
+)
+
+// WithConfigLoadRetry tries to load the kubeconfig up to attempts times, waiting delay between the attempts,
+// in case it is still being written by another process. 0 keeps the defaults of 3 attempts 1s apart,
+// 1 attempt disables the retry. It is used only by NewKubernetesAPIFromFile.
+func WithConfigLoadRetry(attempts int, delay time.Duration) Option {
+	return func(kube *kubernetes) {
+		kube.configLoadAttempts = attempts
+		kube.configLoadDelay = delay
+	}
+}
+
+// NewKubernetesAPIFromFile works like NewKubernetesAPI but returns the error of loading the kubeconfig
+// instead of exiting, the load is retried as set by WithConfigLoadRetry
+func NewKubernetesAPIFromFile(kubeConfigPath string, codefresh codefresh.API, reporter reporter.Reporter, opts ...Option) (API, error) {
+	kube := NewKubernetesAPIFromConfig(api.NewConfig(), codefresh, reporter, opts...).(*kubernetes)
+	config, err := loadConfigWithRetry(kubeConfigPath, kube.configLoadAttempts, kube.configLoadDelay)
+	if err != nil {
+		return nil, err
+	}
+	kube.config = config
+	return kube, nil
+}
+
+func loadConfigWithRetry(path string, attempts int, delay time.Duration) (*api.Config, error) {
+	if attempts <= 0 {
+		attempts = defaultConfigLoadAttempts
+	}
+	if delay <= 0 {
+		delay = defaultConfigLoadDelay
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			log.WithField("attempt", attempt).Warn(fmt.Sprintf("Failed to load kubeconfig with error:\n%s\nRetrying in %s", err, delay))
+			time.Sleep(delay)
+		}
+		var config *api.Config
+		config, err = clientcmd.LoadFromFile(path)
+		if err == nil {
+			return config, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// batchedOutputMu keeps the batches of the contexts that are done at the same time from interleaving
+var batchedOutputMu sync.Mutex
+
+// WithLogDir writes the logs of each context to <dir>/<context>.log in addition to the main stream
+func WithLogDir(dir string) Option {
+	return func(kube *kubernetes) {
+		kube.logDir = dir
+	}
+}
+
+// WithBatchedLogs buffers the logs of each context and writes them to the main stream at once when
+// the context is done, so the lines of a context stay together when the contexts are processed in parallel.
+// By default the lines are streamed as they are logged.
+func WithBatchedLogs(batched bool) Option {
+	return func(kube *kubernetes) {
+		kube.batchedLogs = batched
+	}
+}
+
+// contextLogger creates the logger of a single context, the returned function must be called
+// once the context is done to release the log file and flush the batched lines
+func (kube *kubernetes) contextLogger(contextName string, fields log.Fields) (*log.Entry, func()) {
+	std := log.StandardLogger()
+	if kube.logDir == "" && !kube.batchedLogs {
+		return std.WithFields(fields), func() {}
+	}
+	out := std.Out
+	var batch *bytes.Buffer
+	if kube.batchedLogs {
+		batch = &bytes.Buffer{}
+		out = batch
+	}
+	var file *os.File
+	if kube.logDir != "" {
+		file = openContextLogFile(kube.logDir, contextName)
+	}
+	if file != nil {
+		out = io.MultiWriter(out, file)
+	}
+	logger := log.New()
+	logger.Out = out
+	logger.Formatter = std.Formatter
+	logger.Level = std.Level
+	return logger.WithFields(fields), func() {
+		if file != nil {
+			file.Close()
+		}
+		if batch != nil {
+			batchedOutputMu.Lock()
+			defer batchedOutputMu.Unlock()
+			batch.WriteTo(std.Out)
+		}
+	}
+}
+
+// openContextLogFile returns nil when the file could not be opened, the context is then logged only to the main stream
+func openContextLogFile(dir string, contextName string) *os.File {
+	std := log.StandardLogger()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		std.Warn(fmt.Sprintf("Failed to create log directory with error:\n%s", err))
+		return nil
+	}
+	path := filepath.Join(dir, unsafeFileNameChars.ReplaceAllString(contextName, "_")+".log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		std.Warn(fmt.Sprintf("Failed to open log file %s with error:\n%s", path, err))
+		return nil
+	}
+	return file
+}
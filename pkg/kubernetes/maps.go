@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"sigs.k8s.io/yaml"
+)
+
+// LoadContextMap reads a YAML file in the form of:
+//
+//	<context-name>: <value>
+func LoadContextMap(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]string{}
+	err = yaml.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadContextList reads a file with a context name per line, empty lines and lines starting with # are ignored
+func LoadContextList(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// WithNameMap sets under which name each context is saved in Codefresh by GoOverAllContexts,
+// unmapped contexts are saved under the context name
+func WithNameMap(names map[string]string) Option {
+	return func(kube *kubernetes) {
+		kube.nameMap = names
+	}
+}
+
+// WithNamespaceMap sets the namespace of the service account of each context for GoOverAllContexts,
+// unmapped contexts use the namespace set by WithServiceAccount
+func WithNamespaceMap(namespaces map[string]string) Option {
+	return func(kube *kubernetes) {
+		kube.namespaceMap = namespaces
+	}
+}
+
+// WithContextEnvPrefix reads the namespace and the service account of each context of GoOverAllContexts
+// from the environment, e.g. <prefix>_PROD_US_NAMESPACE and <prefix>_PROD_US_SERVICEACCOUNT for context prod-us.
+// They take precedence over WithServiceAccount and the context extension, the namespace map takes precedence over them.
+func WithContextEnvPrefix(prefix string) Option {
+	return func(kube *kubernetes) {
+		kube.contextEnvPrefix = prefix
+	}
+}
+
+// EnvVarForContext returns the value of the <prefix>_<context>_<field> environment variable,
+// the context name is uppercased and the characters not allowed in environment variable names become "_"
+func EnvVarForContext(prefix string, contextName string, field string) string {
+	name := strings.ToUpper(strings.Join([]string{prefix, contextName, field}, "_"))
+	return os.Getenv(invalidEnvVarChars.ReplaceAllString(name, "_"))
+}
+
+var invalidEnvVarChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// applyContextEnv overrides the namespace and the service account of the context with the ones of the environment
+func applyContextEnv(prefix string, options *getOverContextOptions) {
+	if prefix == "" {
+		return
+	}
+	if namespace := EnvVarForContext(prefix, options.contextName, "NAMESPACE"); namespace != "" {
+		options.namespace = namespace
+	}
+	if serviceaccount := EnvVarForContext(prefix, options.contextName, "SERVICEACCOUNT"); serviceaccount != "" {
+		options.serviceaccount = serviceaccount
+		options.serviceaccounts = nil
+	}
+}
+
+// WithServiceAccount sets the service accounts GoOverAllContexts reads the credentials from, default is default/default.
+// With more than one the first that exists and has a token secret is used.
+func WithServiceAccount(namespace string, serviceaccounts []string) Option {
+	return func(kube *kubernetes) {
+		kube.namespace = namespace
+		if len(serviceaccounts) > 0 {
+			kube.serviceaccounts = serviceaccounts
+		}
+	}
+}
+
+// orDefaultServiceAccount returns the service accounts, or default when there are none
+func orDefaultServiceAccount(serviceaccounts []string) []string {
+	if len(serviceaccounts) == 0 {
+		return []string{"default"}
+	}
+	return serviceaccounts
+}
+
+// reportStaleMappings reports mapped contexts that do not exist in the kubeconfig, so the map stays in sync
+func (kube *kubernetes) reportStaleMappings(mapName string, m map[string]string) {
+	contexts := kube.getConfig().Contexts
+	names := []string{}
+	for contextName := range m {
+		if _, ok := contexts[contextName]; !ok {
+			names = append(names, contextName)
+		}
+	}
+	sort.Strings(names)
+	for _, contextName := range names {
+		kube.reporter.AddToReport(contextName, reporter.FAILED, fmt.Sprintf("Context is mapped in %s but not found in kubeconfig", mapName))
+	}
+}
@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	kubeConfig "k8s.io/client-go/kubernetes"
+)
+
+// recordingExtractor records the service account the token is read from
+type recordingExtractor struct {
+	namespace      string
+	serviceaccount string
+}
+
+func (x *recordingExtractor) Extract(ctx context.Context, clientset kubeConfig.Interface, opts ExtractOptions) ([]byte, []byte, error) {
+	x.namespace = opts.Namespace
+	x.serviceaccount = opts.ServiceAccount
+	return []byte("token"), []byte("ca"), nil
+}
+
+func TestGoOverContextByNameWithoutServiceAccounts(t *testing.T) {
+	extractor := &recordingExtractor{}
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(testConfig(1), &fakeCodefresh{}, rep,
+		WithCredentialExtractor(extractor),
+	)
+
+	kube.GoOverContextByName("ctx-0", "default", nil, false, "ctx-0")
+
+	if extractor.serviceaccount != "default" {
+		t.Errorf("expected the default service account, got %q", extractor.serviceaccount)
+	}
+	if entries := rep.Entries(); len(entries) != 1 || entries[0].Status != reporter.SUCCESS {
+		t.Errorf("expected the context to be added, got %+v", entries)
+	}
+}
+
+func TestGoOverCurrentContextResolvesServiceAccount(t *testing.T) {
+	os.Setenv("STEVEDORE_CTX_0_SERVICEACCOUNT", "from-env")
+	defer os.Unsetenv("STEVEDORE_CTX_0_SERVICEACCOUNT")
+	config := testConfig(2)
+	config.CurrentContext = "ctx-0"
+	extractor := &recordingExtractor{}
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(config, &fakeCodefresh{}, rep,
+		WithCredentialExtractor(extractor),
+		WithServiceAccount("apps", []string{"deployer"}),
+		WithContextEnvPrefix("STEVEDORE"),
+	)
+
+	kube.GoOverCurrentContext()
+
+	if extractor.namespace != "apps" || extractor.serviceaccount != "from-env" {
+		t.Errorf("expected apps/from-env, got %s/%s", extractor.namespace, extractor.serviceaccount)
+	}
+	if entries := rep.Entries(); len(entries) != 1 || entries[0].Name != "ctx-0" || entries[0].Status != reporter.SUCCESS {
+		t.Errorf("expected the current context to be added, got %+v", entries)
+	}
+}
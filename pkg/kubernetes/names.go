@@ -0,0 +1,35 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// invalidNameError is returned when the Codefresh name of the context violates the naming convention,
+// retrying it is pointless
+type invalidNameError struct {
+	reason string
+}
+
+func (e *invalidNameError) Error() string {
+	return e.reason
+}
+
+// WithNameValidation reports the contexts whose Codefresh name does not match the pattern
+// or is longer than maxLength as INVALID_NAME before anything is created, nil pattern and 0 disable the checks
+func WithNameValidation(pattern *regexp.Regexp, maxLength int) Option {
+	return func(kube *kubernetes) {
+		kube.namePattern = pattern
+		kube.nameMaxLength = maxLength
+	}
+}
+
+func validateName(options *getOverContextOptions) error {
+	if options.nameMaxLength > 0 && len(options.name) > options.nameMaxLength {
+		return &invalidNameError{reason: fmt.Sprintf("Name %s is longer than %d characters", options.name, options.nameMaxLength)}
+	}
+	if options.namePattern != nil && !options.namePattern.MatchString(options.name) {
+		return &invalidNameError{reason: fmt.Sprintf("Name %s does not match %s", options.name, options.namePattern.String())}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+)
+
+// WithNodeCount lists the nodes of each cluster and adds their number to the meta of the context as node_count,
+// a cluster without nodes can not run builds. Failing to list them, e.g. without RBAC on nodes, does not fail the context.
+func WithNodeCount(include bool) Option {
+	return func(kube *kubernetes) {
+		kube.includeNodeCount = include
+	}
+}
+
+func countNodes(clientset kubeConfig.Interface, options *getOverContextOptions) {
+	nodes, e := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if e != nil {
+		options.logger.Warn(fmt.Sprintf("Failed to list nodes with error:\n%s", e))
+		return
+	}
+	count := len(nodes.Items)
+	options.logger.WithField("node_count", count).Debug("Counted nodes of the cluster")
+	options.meta["node_count"] = strconv.Itoa(count)
+}
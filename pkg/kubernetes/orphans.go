@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+)
+
+// expectedClusterNames returns the names the contexts of the kubeconfig are saved under in Codefresh
+func (kube *kubernetes) expectedClusterNames() map[string]bool {
+	names := map[string]bool{}
+	for contextName := range kube.getConfig().Contexts {
+		name := contextName
+		if mapped, ok := kube.nameMap[contextName]; ok {
+			name = mapped
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// GoReportOrphanedClusters reports clusters that are registered in Codefresh but have no context in the kubeconfig,
+// they are deleted from Codefresh only when deleteOrphans is set
+func (kube *kubernetes) GoReportOrphanedClusters(deleteOrphans bool) error {
+	ctx := context.Background()
+	clusters, err := kube.codefresh.List(ctx)
+	if err != nil {
+		return err
+	}
+	expected := kube.expectedClusterNames()
+	for _, cluster := range clusters {
+		if expected[cluster.Selector] {
+			continue
+		}
+		logger := log.WithFields(log.Fields{
+			"name": cluster.Selector,
+			"host": cluster.Host,
+		})
+		logger.Info("Cluster is not in kubeconfig")
+		if !deleteOrphans {
+			kube.reporter.AddToReport(cluster.Selector, reporter.ORPHANED, "")
+			continue
+		}
+		err := kube.codefresh.Delete(ctx, cluster.Selector)
+		if err != nil {
+			message := fmt.Sprintf("Failed to delete cluster with error:\n%s", err)
+			logger.Error(message)
+			kube.reporter.AddToReport(cluster.Selector, reporter.ORPHANED, message)
+			continue
+		}
+		logger.Info("Cluster deleted!")
+		kube.reporter.AddToReport(cluster.Selector, reporter.ORPHANED, "Deleted from Codefresh")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+type (
+	// ContextOverride holds settings for a single context that take precedence over the global ones
+	ContextOverride struct {
+		StorageClassName string `json:"storageClassName,omitempty"`
+		ReclaimPolicy    string `json:"reclaimPolicy,omitempty"`
+		AgentNamespace   string `json:"agentNamespace,omitempty"`
+		// AgentServiceAccount is in AgentNamespace
+		AgentServiceAccount string `json:"agentServiceAccount,omitempty"`
+		// Targets replace the integrations of WithRegistrationTargets for the context
+		Targets []RegistrationTarget `json:"targets,omitempty"`
+		// Timeout replaces the WithContextTimeout of the context, e.g. 10m, it is reported in the meta as context_timeout
+		Timeout string `json:"timeout,omitempty"`
+	}
+
+	// ContextOverrides maps context name to its overrides
+	ContextOverrides map[string]ContextOverride
+)
+
+// LoadContextOverrides reads a YAML file in the form of:
+//
+//	<context-name>:
+//	  storageClassName: <name>
+//	  reclaimPolicy: <policy>
+//	  agentNamespace: <namespace>
+//	  agentServiceAccount: <name>
+//	  targets:
+//	  - scope: <team>
+//	    namespaces: [<namespace>, ...]
+//	  timeout: <duration>
+func LoadContextOverrides(path string) (ContextOverrides, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	overrides := ContextOverrides{}
+	err = yaml.Unmarshal(data, &overrides)
+	if err != nil {
+		return nil, err
+	}
+	for contextName, override := range overrides {
+		if override.Timeout == "" {
+			continue
+		}
+		_, err = time.ParseDuration(override.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid timeout of context %s: %s", contextName, err)
+		}
+	}
+	return overrides, nil
+}
+
+// WithStorage sets the build volume configuration for all the clusters
+func WithStorage(storageClassName string, reclaimPolicy string) Option {
+	return func(kube *kubernetes) {
+		kube.storageClassName = storageClassName
+		kube.reclaimPolicy = reclaimPolicy
+	}
+}
+
+// WithAgentNamespace sets the namespace the Codefresh agent is installed in for clusters behind firewall
+func WithAgentNamespace(namespace string) Option {
+	return func(kube *kubernetes) {
+		kube.agentNamespace = namespace
+	}
+}
+
+// WithAgentServiceAccount sets the service account the Codefresh agent runs with in the agent namespace,
+// independently from the service account the token of the cluster is taken from
+func WithAgentServiceAccount(name string) Option {
+	return func(kube *kubernetes) {
+		kube.agentServiceAccount = name
+	}
+}
+
+// WithContextOverrides sets per context settings
+func WithContextOverrides(overrides ContextOverrides) Option {
+	return func(kube *kubernetes) {
+		kube.overrides = overrides
+	}
+}
+
+// applySettings fills the options with the global settings and the overrides of the context
+func (kube *kubernetes) applySettings(options *getOverContextOptions) {
+	options.teamNames = kube.teamNames
+	options.storageClassName = kube.storageClassName
+	options.reclaimPolicy = kube.reclaimPolicy
+	options.includeProviders = kube.includeProviders
+	options.excludeProviders = kube.excludeProviders
+	options.meta = map[string]string{}
+	options.tokenExpirySeconds = kube.tokenExpirySeconds
+	options.tokenAudience = kube.tokenAudience
+	options.strictAudience = kube.strictAudience
+	// the dry run does not change the clusters
+	options.autoCreateSA = kube.autoCreateSA && !kube.dryRun
+	options.cleanupOnFailure = kube.cleanupOnFailure
+	options.saClusterRole = kube.saClusterRole
+	options.fingerprints = kube.fingerprints
+	options.runID = kube.runID
+	options.forceOverwrite = kube.forceOverwrite
+	options.dryRun = kube.dryRun
+	options.refreshTokens = kube.refreshTokens
+	options.targets = kube.targets
+	options.clientFactory = kube.clientFactory
+	options.credentialExtractor = kube.credentialExtractor
+	options.includeNodeCount = kube.includeNodeCount
+	options.secretFieldSelector = kube.secretFieldSelector
+	options.globalTLSCABundle = kube.globalTLSCABundle
+	options.transportWrapper = kube.transportWrapper
+	options.inClusterTokenFile = kube.inClusterTokenFile
+	options.inClusterCAFile = kube.inClusterCAFile
+	options.namePattern = kube.namePattern
+	options.nameMaxLength = kube.nameMaxLength
+	options.clusterInfo = kube.clusterInfo
+	options.agentNamespace = kube.agentNamespace
+	options.agentServiceAccount = kube.agentServiceAccount
+	options.insecureHosts = kube.insecureHosts
+	options.serverOverrides = kube.serverOverrides
+	options.smokeTestPipeline = kube.smokeTestPipeline
+	options.smokeTestTimeout = kube.smokeTestTimeout
+	options.contextTimeout = kube.contextTimeout
+	options.runnerCheck = kube.runnerCheck
+	options.runnerCheckTimeout = kube.runnerCheckTimeout
+	override, ok := kube.overrides[options.contextName]
+	if !ok {
+		return
+	}
+	if override.StorageClassName != "" {
+		options.storageClassName = override.StorageClassName
+	}
+	if override.ReclaimPolicy != "" {
+		options.reclaimPolicy = override.ReclaimPolicy
+	}
+	if override.AgentNamespace != "" {
+		options.agentNamespace = override.AgentNamespace
+	}
+	if override.AgentServiceAccount != "" {
+		options.agentServiceAccount = override.AgentServiceAccount
+	}
+	if len(override.Targets) > 0 {
+		options.targets = override.Targets
+	}
+	if override.Timeout != "" {
+		// validated by LoadContextOverrides
+		timeout, _ := time.ParseDuration(override.Timeout)
+		options.contextTimeout = timeout
+		options.meta["context_timeout"] = timeout.String()
+	}
+}
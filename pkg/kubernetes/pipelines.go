@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	log "github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// readPipelineTemplates splits a multi document YAML file into pipeline templates
+func readPipelineTemplates(templatePath string) ([][]byte, error) {
+	data, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	templates := [][]byte{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(doc)) == "" {
+			continue
+		}
+		templates = append(templates, doc)
+	}
+	return templates, nil
+}
+
+// GoCreatePipelinesForAllContexts creates the pipelines from the template file for each context,
+// {{.ClusterName}} in the template is replaced with the name the cluster is saved under in Codefresh,
+// resolved the same way as by GoOverAllContexts
+func (kube *kubernetes) GoCreatePipelinesForAllContexts(templatePath string) error {
+	templates, err := readPipelineTemplates(templatePath)
+	if err != nil {
+		return err
+	}
+	contextNames := []string{}
+	for contextName := range kube.getConfig().Contexts {
+		contextNames = append(contextNames, contextName)
+	}
+	sort.Strings(contextNames)
+	errs := []error{}
+	for _, contextName := range contextNames {
+		logger := log.WithFields(log.Fields{
+			"context_name": contextName,
+		})
+		options := &getOverContextOptions{
+			contextName: contextName,
+			name:        contextName,
+			namespace:   kube.namespace,
+		}
+		err := kube.resolveContext(kube.getConfig(), options)
+		if err != nil {
+			logger.Error(err.Error())
+			errs = append(errs, fmt.Errorf("Context %s: %s", contextName, err))
+			continue
+		}
+		logger = logger.WithField("name", options.name)
+		for _, spec := range templates {
+			logger.Info("Creating pipeline in Codefresh")
+			id, err := kube.codefresh.CreatePipeline(codefresh.PipelineOptions{
+				Spec:        spec,
+				ClusterName: options.name,
+			})
+			if err != nil {
+				message := fmt.Sprintf("Failed to create pipeline with error:\n%s", err)
+				logger.Error(message)
+				errs = append(errs, fmt.Errorf("Context %s: %s", contextName, err))
+				continue
+			}
+			logger.WithField("pipeline_id", id).Info("Pipeline created!")
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,48 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// pipelineCodefresh records the cluster names the pipelines are created for
+type pipelineCodefresh struct {
+	fakeCodefresh
+	clusterNames []string
+}
+
+func (f *pipelineCodefresh) CreatePipeline(opt codefresh.PipelineOptions) (string, error) {
+	f.clusterNames = append(f.clusterNames, opt.ClusterName)
+	return "id", nil
+}
+
+func TestPipelinesUseTheResolvedClusterName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipelines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	template := filepath.Join(dir, "pipeline.yaml")
+	err = ioutil.WriteFile(template, []byte("kind: pipeline\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf := &pipelineCodefresh{}
+	kube := NewKubernetesAPIFromConfig(testConfig(2), cf, reporter.NewReporter(),
+		WithNameMap(map[string]string{"ctx-1": "production"}),
+	)
+
+	err = kube.GoCreatePipelinesForAllContexts(template)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cf.clusterNames) != 2 || cf.clusterNames[0] != "ctx-0" || cf.clusterNames[1] != "production" {
+		t.Errorf("expected the pipelines of ctx-0 and production, got %v", cf.clusterNames)
+	}
+}
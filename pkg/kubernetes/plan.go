@@ -0,0 +1,141 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// ContextPlan is what GoOverAllContexts would do with a context, computed without reaching the cluster
+type ContextPlan struct {
+	Context         string   `json:"context"`
+	Name            string   `json:"name"`
+	Namespace       string   `json:"namespace"`
+	ServiceAccounts []string `json:"serviceAccounts"`
+	BehindFirewall  bool     `json:"behindFirewall"`
+	Excluded        bool     `json:"excluded,omitempty"`
+	// Clashes are the other planned contexts that would be added with the same Codefresh name
+	Clashes []string `json:"clashes,omitempty"`
+	// Error is set when the context extension can not be read
+	Error string `json:"error,omitempty"`
+}
+
+// plan resolves every context of the kubeconfig the same way goOverContextInConfig does, sorted by context name
+func (kube *kubernetes) plan() []ContextPlan {
+	rawConfig := kube.getConfig()
+	names := []string{}
+	for contextName := range rawConfig.Contexts {
+		names = append(names, contextName)
+	}
+	sort.Strings(names)
+	plans := []ContextPlan{}
+	for _, contextName := range names {
+		options := &getOverContextOptions{
+			contextName: contextName,
+			name:        contextName,
+			namespace:   kube.namespace,
+		}
+		options.serviceaccounts = orDefaultServiceAccount(kube.serviceaccounts)
+		options.serviceaccount = options.serviceaccounts[0]
+		p := ContextPlan{
+			Context:  contextName,
+			Excluded: kube.isExcluded(contextName),
+		}
+		err := kube.resolveContext(rawConfig, options)
+		if err != nil {
+			p.Error = err.Error()
+		}
+		p.Name = options.name
+		p.Namespace = options.namespace
+		p.ServiceAccounts = options.serviceaccounts
+		if len(p.ServiceAccounts) == 0 {
+			p.ServiceAccounts = []string{options.serviceaccount}
+		}
+		p.BehindFirewall = options.behindFirewall
+		plans = append(plans, p)
+	}
+	clashes := DuplicateNames(plans)
+	for i := range plans {
+		for _, other := range clashes[normalizeName(plans[i].Name)] {
+			if other != plans[i].Context && !plans[i].Excluded && plans[i].Error == "" {
+				plans[i].Clashes = append(plans[i].Clashes, other)
+			}
+		}
+	}
+	return plans
+}
+
+// normalizeName is the form two names are compared in, Codefresh does not tell names apart by case
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// DuplicateNames maps each normalized Codefresh name planned for more than one context to those contexts,
+// excluded contexts and the ones whose plan failed are not added so they can not clash
+func DuplicateNames(plans []ContextPlan) map[string][]string {
+	byName := map[string][]string{}
+	for _, p := range plans {
+		if p.Excluded || p.Error != "" {
+			continue
+		}
+		name := normalizeName(p.Name)
+		byName[name] = append(byName[name], p.Context)
+	}
+	duplicates := map[string][]string{}
+	for name, contexts := range byName {
+		if len(contexts) > 1 {
+			sort.Strings(contexts)
+			duplicates[name] = contexts
+		}
+	}
+	return duplicates
+}
+
+// checkDuplicateNames logs the clashing contexts before any context is processed and keeps them
+// for goOverContextInConfig to fail, so none of them overwrites or conflicts with another
+func (kube *kubernetes) checkDuplicateNames() {
+	kube.clashes = map[string][]string{}
+	for _, p := range kube.plan() {
+		if len(p.Clashes) == 0 {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"context_name": p.Context,
+			"clashes":      strings.Join(p.Clashes, ","),
+		}).Error(fmt.Sprintf("Context %s would be added as %s like other contexts", p.Context, p.Name))
+		kube.clashes[p.Context] = p.Clashes
+	}
+}
+
+// PrintContextList writes the plan of all the contexts to w as yaml, json or table, no context is processed
+func (kube *kubernetes) PrintContextList(w io.Writer, format string) error {
+	plans := kube.plan()
+	switch format {
+	case "yaml":
+		data, err := yaml.Marshal(plans)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plans)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CONTEXT\tNAME\tNAMESPACE\tSERVICE ACCOUNTS\tBEHIND FIREWALL\tEXCLUDED\tCLASHES\tERROR")
+		for _, p := range plans {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%t\t%s\t%s\n", p.Context, p.Name, p.Namespace, strings.Join(p.ServiceAccounts, ","), p.BehindFirewall, p.Excluded, strings.Join(p.Clashes, ","), p.Error)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("Unknown context list format %s", format)
+	}
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+)
+
+// PreflightReportName is the name of the report entry of a failed pre-flight check
+const PreflightReportName = "preflight"
+
+// PreflightCheck runs once before GoOverAllContexts and GoOverContextGroups process the first context,
+// an error aborts the run
+type PreflightCheck func(ctx context.Context, kube *kubernetes) error
+
+// WithPreflightCheck sets the check run before the contexts are processed, compose several with All
+func WithPreflightCheck(check PreflightCheck) Option {
+	return func(kube *kubernetes) {
+		kube.preflightCheck = check
+	}
+}
+
+// All runs the checks in order and stops at the first failure
+func All(checks ...PreflightCheck) PreflightCheck {
+	return func(ctx context.Context, kube *kubernetes) error {
+		for _, check := range checks {
+			if err := check(ctx, kube); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CodefreshTokenValidation fails when Codefresh is not reachable or rejects the token
+func CodefreshTokenValidation() PreflightCheck {
+	return func(ctx context.Context, kube *kubernetes) error {
+		return kube.codefresh.Ping(ctx)
+	}
+}
+
+// KubeconfigMinContexts fails when the kubeconfig has fewer than n contexts,
+// e.g. when it was generated only partially
+func KubeconfigMinContexts(n int) PreflightCheck {
+	return func(ctx context.Context, kube *kubernetes) error {
+		if count := len(kube.getConfig().Contexts); count < n {
+			return fmt.Errorf("Kubeconfig has %d contexts, at least %d are required", count, n)
+		}
+		return nil
+	}
+}
+
+// runPreflight reports RUN_FAILED when the pre-flight check fails, no context is processed then
+func (kube *kubernetes) runPreflight(ctx context.Context) error {
+	if kube.preflightCheck == nil {
+		return nil
+	}
+	err := kube.preflightCheck(ctx, kube)
+	if err != nil {
+		log.Error(fmt.Sprintf("Pre-flight check failed, no context is processed:\n%s", err))
+		kube.reporter.AddToReport(PreflightReportName, reporter.RUN_FAILED, err.Error())
+	}
+	return err
+}
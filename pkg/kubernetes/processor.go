@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ContextProcessor adds a single context to Codefresh without the rest of the kubernetes API,
+// so the core logic can be driven directly with a fake Codefresh API and reporter
+type ContextProcessor struct {
+	ContextName string
+	// Name the cluster is saved under in Codefresh, default is ContextName
+	Name           string
+	Namespace      string
+	ServiceAccount string
+	BehindFirewall bool
+	Config         clientcmd.ClientConfig
+	Codefresh      codefresh.API
+	Reporter       reporter.Reporter
+	// Logger is optional, default is the standard logger
+	Logger *log.Entry
+	// Options are the settings accepted by NewKubernetesAPI, e.g. WithTeamNames
+	Options []Option
+}
+
+// Process makes a single attempt, only its success is reported, the error is returned to the caller
+// instead of being retried or reported
+func (p *ContextProcessor) Process(ctx context.Context) error {
+	kube := &kubernetes{
+		codefresh: p.Codefresh,
+		reporter:  p.Reporter,
+		runID:     NewRunID(),
+	}
+	for _, opt := range p.Options {
+		opt(kube)
+	}
+	options := &getOverContextOptions{
+		contextName:    p.ContextName,
+		namespace:      p.Namespace,
+		serviceaccount: p.ServiceAccount,
+		config:         p.Config,
+		logger:         p.Logger,
+		codefresh:      p.Codefresh,
+		reporter:       p.Reporter,
+		behindFirewall: p.BehindFirewall,
+		name:           p.Name,
+	}
+	if options.name == "" {
+		options.name = p.ContextName
+	}
+	if options.logger == nil {
+		options.logger = log.WithField("context_name", p.ContextName)
+	}
+	kube.applySettings(options)
+	return goOverContext(ctx, options)
+}
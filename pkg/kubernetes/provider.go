@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+)
+
+const (
+	ProviderEKS     = "eks"
+	ProviderGKE     = "gke"
+	ProviderAKS     = "aks"
+	ProviderUnknown = "unknown"
+)
+
+type (
+	// skippedError is returned when the context should not be added, retrying it is pointless
+	skippedError struct {
+		reason string
+	}
+
+	providerMarkers struct {
+		provider string
+		// version is a part of the server git version, e.g. v1.11.5-eks-6bad6d
+		version string
+		// group is a suffix of an API group served only by the provider
+		group string
+		// label is a prefix of a node label set only by the provider
+		label string
+	}
+)
+
+var knownProviders = []providerMarkers{
+	{
+		provider: ProviderEKS,
+		version:  "-eks-",
+		group:    "k8s.amazonaws.com",
+		label:    "eks.amazonaws.com/",
+	},
+	{
+		provider: ProviderGKE,
+		version:  "-gke.",
+		group:    "gke.io",
+		label:    "cloud.google.com/gke-",
+	},
+	{
+		provider: ProviderAKS,
+		version:  "",
+		group:    "azure.com",
+		label:    "kubernetes.azure.com/",
+	},
+}
+
+func (e *skippedError) Error() string {
+	return e.reason
+}
+
+// WithProviderFilter adds only the clusters whose detected provider is in include (when set) and not in exclude
+func WithProviderFilter(include []string, exclude []string) Option {
+	return func(kube *kubernetes) {
+		kube.includeProviders = include
+		kube.excludeProviders = exclude
+	}
+}
+
+// DetectProvider inspects the live cluster to find out which cloud provider runs it.
+// Server version is checked first, then the served API groups and at last the labels of a node.
+func DetectProvider(clientset kubeConfig.Interface) string {
+	version, err := clientset.Discovery().ServerVersion()
+	if err == nil {
+		for _, m := range knownProviders {
+			if m.version != "" && strings.Contains(version.GitVersion, m.version) {
+				return m.provider
+			}
+		}
+	}
+	groups, err := clientset.Discovery().ServerGroups()
+	if err == nil {
+		for _, g := range groups.Groups {
+			for _, m := range knownProviders {
+				if strings.HasSuffix(g.Name, m.group) {
+					return m.provider
+				}
+			}
+		}
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(metav1.ListOptions{Limit: 1})
+	if err == nil {
+		for _, n := range nodes.Items {
+			for label := range n.Labels {
+				for _, m := range knownProviders {
+					if strings.HasPrefix(label, m.label) {
+						return m.provider
+					}
+				}
+			}
+		}
+	}
+	return ProviderUnknown
+}
+
+func checkProvider(provider string, include []string, exclude []string) error {
+	for _, p := range exclude {
+		if p == provider {
+			return &skippedError{reason: fmt.Sprintf("Provider %s is excluded", provider)}
+		}
+	}
+	if len(include) == 0 {
+		return nil
+	}
+	for _, p := range include {
+		if p == provider {
+			return nil
+		}
+	}
+	return &skippedError{reason: fmt.Sprintf("Provider %s is not included", provider)}
+}
@@ -0,0 +1,50 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// WithTokenRefresh updates the clusters that already exist in Codefresh when Codefresh can not reach them
+// with the stored credentials, e.g. the token expired, with the token that was just extracted.
+// The clusters that still work are reported as UNCHANGED instead of FAILED_CONFLICT.
+func WithTokenRefresh(refresh bool) Option {
+	return func(kube *kubernetes) {
+		kube.refreshTokens = refresh
+	}
+}
+
+// refreshIfStale returns UNCHANGED when the existing cluster is healthy, otherwise it is patched
+// and REFRESHED is returned. Clusters behind firewall are checked with the status of their agent
+// as Codefresh can not reach them directly.
+func refreshIfStale(ctx context.Context, options *getOverContextOptions, createOptions *codefresh.CreateOptions) ([]byte, string, error) {
+	existing, e := options.codefresh.GetCluster(ctx, options.name)
+	if e != nil {
+		message := fmt.Sprintf("Failed to get existing cluster with error:\n%s", e)
+		options.logger.Warn(message)
+		return nil, "", e
+	}
+	if existing.BehindFirewall {
+		agent, err := options.codefresh.GetAgentStatus(ctx, options.name)
+		if err == nil && agent.Status != codefresh.AgentStatusHealthy {
+			err = fmt.Errorf("Agent status is %s", agent.Status)
+		}
+		e = err
+	} else {
+		e = codefresh.TestExisting(ctx, options.codefresh, existing)
+	}
+	if e == nil {
+		options.logger.Info(fmt.Sprintf("Cluster %s already exists in Codefresh and is healthy", options.name))
+		return nil, reporter.UNCHANGED, nil
+	}
+	options.logger.Info(fmt.Sprintf("Cluster %s already exists in Codefresh but is not reachable, refreshing its token: %s", options.name, e))
+	result, e := options.codefresh.PatchCluster(ctx, createOptions)
+	if e != nil {
+		return nil, "", e
+	}
+	options.meta["token_refreshed"] = "true"
+	return result, reporter.REFRESHED, nil
+}
@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+)
+
+// ResultsConfigMapName is the ConfigMap written by WithResultsConfigMap
+const ResultsConfigMapName = "stevedore-results"
+
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var invalidConfigMapKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// WithResultsConfigMap writes the Codefresh id of each added cluster to the stevedore-results ConfigMap
+// of the cluster stevedore runs in once GoOverAllContexts is done, keyed by context name.
+// Empty namespace is the namespace stevedore runs in.
+func WithResultsConfigMap(write bool, namespace string) Option {
+	return func(kube *kubernetes) {
+		kube.writeResultsConfigMap = write
+		kube.resultsConfigMapNamespace = namespace
+	}
+}
+
+// ConfigMapKey turns the context name into a valid ConfigMap key, characters ConfigMap keys
+// can not hold (e.g. ":" and "/" of EKS context names) are replaced with "_"
+func ConfigMapKey(contextName string) string {
+	return invalidConfigMapKeyChars.ReplaceAllString(contextName, "_")
+}
+
+// resultsData maps the contexts that were added to Codefresh to the ids of their clusters
+func resultsData(entries []reporter.ReportEntry) map[string]string {
+	data := map[string]string{}
+	for _, entry := range entries {
+		if !reporter.OnlySucceeded(entry) || entry.Meta["cluster_id"] == "" {
+			continue
+		}
+		data[ConfigMapKey(entry.Name)] = entry.Meta["cluster_id"]
+	}
+	return data
+}
+
+// saveResults writes the ConfigMap, failing to write it does not fail the run
+func (kube *kubernetes) saveResults() {
+	if !kube.writeResultsConfigMap || kube.dryRun {
+		return
+	}
+	err := kube.writeResults()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to write %s ConfigMap with error:\n%s", ResultsConfigMapName, err))
+	}
+}
+
+func (kube *kubernetes) writeResults() error {
+	namespace := kube.resultsConfigMapNamespace
+	if namespace == "" {
+		data, err := ioutil.ReadFile(inClusterNamespaceFile)
+		if err != nil {
+			return err
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	config, err := inClusterConfig(kube.inClusterTokenFile, kube.inClusterCAFile)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubeConfig.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	data := resultsData(kube.reporter.Entries())
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ResultsConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ResultsConfigMapName,
+				Namespace: namespace,
+			},
+			Data: data,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	cm.Data = data
+	_, err = configMaps.Update(cm)
+	return err
+}
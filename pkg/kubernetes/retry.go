@@ -0,0 +1,167 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// WithContextTimeout bounds the total time spent on a single context, retries included
+func WithContextTimeout(timeout time.Duration) Option {
+	return func(kube *kubernetes) {
+		kube.contextTimeout = timeout
+	}
+}
+
+// WithForceOverwrite updates clusters that already exist in Codefresh instead of failing on the conflict
+func WithForceOverwrite(overwrite bool) Option {
+	return func(kube *kubernetes) {
+		kube.forceOverwrite = overwrite
+	}
+}
+
+// WithRenameFrom deletes the cluster registered in Codefresh under the previous name before adding the context under its name,
+// e.g. after the name template changed. It is destructive: Codefresh re-syncs the cluster and the pipelines
+// referring to the previous name stop working. It is used by GoOverContextByName only.
+func WithRenameFrom(previousName string) Option {
+	return func(kube *kubernetes) {
+		kube.renameFrom = previousName
+	}
+}
+
+// renameCluster deletes the cluster of the previous name, it is recorded in the meta as renamed_from
+func renameCluster(ctx context.Context, options *getOverContextOptions) error {
+	options.logger.WithField("renamed_from", options.renameFrom).Info(fmt.Sprintf("Renaming cluster %s to %s in Codefresh", options.renameFrom, options.name))
+	err := options.codefresh.Delete(ctx, options.renameFrom)
+	if err != nil {
+		options.logger.Error(fmt.Sprintf("Failed to delete cluster %s with error:\n%s", options.renameFrom, err))
+		return err
+	}
+	options.meta["renamed_from"] = options.renameFrom
+	return nil
+}
+
+// WithRetries retries a failed context up to retries times, waiting delay between the attempts
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(kube *kubernetes) {
+		kube.retries = retries
+		kube.retryDelay = delay
+	}
+}
+
+// validationError is a problem of the context itself, another attempt fails the same way
+type validationError struct {
+	message string
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+// nonRetryable returns true for the errors another attempt can not fix, they fail the context right away
+func nonRetryable(err error) bool {
+	if err == ErrNoAuthInfo || err == ErrNoSuitableServiceAccount {
+		return true
+	}
+	_, ok := err.(*validationError)
+	return ok
+}
+
+// processContext runs goOverContext with retries and reports the failure if all the attempts failed.
+// The deadline is created once, so all the attempts share the same time budget.
+func (kube *kubernetes) processContext(parent context.Context, options *getOverContextOptions) {
+	ctx := parent
+	if options.contextTimeout != kube.contextTimeout {
+		options.logger.WithField("context_timeout", options.contextTimeout).Info("Using the timeout of the context override")
+	}
+	if options.contextTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.contextTimeout)
+		defer cancel()
+	}
+	var err error
+	if kube.events != nil {
+		recorder := &statusRecorder{Reporter: options.reporter}
+		options.reporter = recorder
+		defer func() {
+			kube.recordEvent(options, recorder.statuses, err)
+		}()
+	}
+	if kube.debugMode {
+		defer func() {
+			kube.dumpContextState(options, err)
+		}()
+	}
+	for attempt := 0; attempt <= kube.retries; attempt++ {
+		if attempt > 0 {
+			options.logger.WithField("attempt", attempt).Warn(fmt.Sprintf("Retrying in %s", kube.retryDelay))
+			select {
+			case <-ctx.Done():
+			case <-time.After(kube.retryDelay):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		err = goOverContext(ctx, options)
+		if err == nil {
+			return
+		}
+		if _, ok := err.(*skippedError); ok {
+			kube.report(options, reporter.SKIPPED, err.Error())
+			return
+		}
+		if _, ok := err.(*targetsError); ok {
+			// the targets are reported one by one
+			return
+		}
+		if _, ok := err.(*invalidNameError); ok {
+			kube.report(options, reporter.INVALID_NAME, err.Error())
+			return
+		}
+		if err == codefresh.ErrConflict {
+			kube.reportFailure(options, reporter.FAILED_CONFLICT, err.Error(), reporter.CategoryCodefresh)
+			return
+		}
+		if nonRetryable(err) {
+			break
+		}
+	}
+	if err == nil {
+		// the context was done before the first attempt
+		err = ctx.Err()
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		message := fmt.Sprintf("Deadline of %s exceeded, last error:\n%s", options.contextTimeout, err)
+		if parent.Err() != nil {
+			message = fmt.Sprintf("%s, last error:\n%s", RunDeadlineExceededMessage, err)
+		}
+		options.logger.Error(message)
+		kube.reportFailure(options, reporter.DEADLINE_EXCEEDED, message, reporter.CategoryTimeout)
+	case ctx.Err() == context.Canceled:
+		message := fmt.Sprintf("Cancelled, last error:\n%s", err)
+		options.logger.Error(message)
+		kube.reportFailure(options, reporter.FAILED, message, reporter.CategoryOther)
+	case err != nil:
+		kube.reportFailure(options, reporter.FAILED, err.Error(), failureCategory(err))
+	}
+}
+
+func (kube *kubernetes) report(options *getOverContextOptions, status string, message string) {
+	kube.reportFailure(options, status, message, "")
+}
+
+// reportFailure reports the context with the category of its failure
+func (kube *kubernetes) reportFailure(options *getOverContextOptions, status string, message string, category string) {
+	kube.reporter.AddEntry(reporter.ReportEntry{
+		Name:     options.contextName,
+		Status:   status,
+		Message:  message,
+		Category: category,
+		Meta:     options.meta,
+	})
+}
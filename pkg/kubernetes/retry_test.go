@@ -0,0 +1,48 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestProcessContextCancelledBeforeFirstAttempt(t *testing.T) {
+	rep := reporter.NewReporter()
+	kube := &kubernetes{reporter: rep}
+	options := &getOverContextOptions{
+		contextName: "ctx",
+		logger:      log.NewEntry(log.New()),
+		meta:        map[string]string{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	kube.processContext(ctx, options)
+
+	entries := rep.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected a single entry, got %+v", entries)
+	}
+	if entries[0].Status != reporter.FAILED || !strings.Contains(entries[0].Message, context.Canceled.Error()) {
+		t.Errorf("expected the cancellation to be reported, got %+v", entries[0])
+	}
+}
+
+func TestNonRetryable(t *testing.T) {
+	cases := map[error]bool{
+		ErrNoAuthInfo:                                             true,
+		ErrNoSuitableServiceAccount:                               true,
+		&validationError{message: "audience"}:                     true,
+		errors.New("connection refused"):                          false,
+		categorize(reporter.CategoryCodefresh, errors.New("500")): false,
+	}
+	for err, expected := range cases {
+		if nonRetryable(err) != expected {
+			t.Errorf("nonRetryable(%q) expected %t", err, expected)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+const runnerPollInterval = 5 * time.Second
+
+// WithRunnerCheck waits up to timeout for the Codefresh runner to reach each added behind firewall cluster
+// and reports it as RUNNER_CONNECTED, or RUNNER_PENDING when the runner did not connect in time
+func WithRunnerCheck(check bool, timeout time.Duration) Option {
+	return func(kube *kubernetes) {
+		kube.runnerCheck = check
+		kube.runnerCheckTimeout = timeout
+	}
+}
+
+// runnerCheck polls the connectivity of the runner to the added cluster and returns the status to report,
+// the cluster is in Codefresh either way so errors are only reported
+func runnerCheck(ctx context.Context, options *getOverContextOptions) (string, string) {
+	if options.runnerCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.runnerCheckTimeout)
+		defer cancel()
+	}
+	options.logger.Info("Waiting for the runner to reach the cluster")
+	for {
+		status, err := options.codefresh.GetRunnerStatus(ctx, options.name)
+		if err == nil && status.Connected {
+			options.logger.Info("Runner reached the cluster")
+			return reporter.RUNNER_CONNECTED, ""
+		}
+		message := ""
+		if err != nil {
+			message = fmt.Sprintf("Failed to get runner status with error:\n%s", err)
+		} else {
+			message = status.Message
+		}
+		select {
+		case <-ctx.Done():
+			options.logger.Warn(fmt.Sprintf("Runner did not reach the cluster in time. %s", message))
+			return reporter.RUNNER_PENDING, message
+		case <-time.After(runnerPollInterval):
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+)
+
+// WithServerOverrides replaces the server URL of the contexts, e.g. internal DNS names that are not routable
+// from where stevedore runs. The keys are regular expressions matched against the server URL, the values
+// replace the matches and may refer to the groups as $1. Only the first matching pattern in sorted order is applied.
+func WithServerOverrides(overrides map[string]string) Option {
+	return func(kube *kubernetes) {
+		kube.serverOverrides = overrides
+	}
+}
+
+// LoadServerOverrides reads a YAML file in the form of:
+//
+//	<regular expression>: <replacement>
+func LoadServerOverrides(path string) (map[string]string, error) {
+	overrides, err := LoadContextMap(path)
+	if err != nil {
+		return nil, err
+	}
+	for pattern := range overrides {
+		_, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid server pattern %s: %s", pattern, err)
+		}
+	}
+	return overrides, nil
+}
+
+// applyServerOverrides replaces the server of the config with the first override matching it.
+// The name of the original server is kept for the TLS verification, as its certificate is issued for it.
+func applyServerOverrides(config *rest.Config, overrides map[string]string, logger *log.Entry) bool {
+	patterns := []string{}
+	for pattern := range overrides {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		// validated by LoadServerOverrides
+		re, err := regexp.Compile(pattern)
+		if err != nil || !re.MatchString(config.Host) {
+			continue
+		}
+		server := re.ReplaceAllString(config.Host, overrides[pattern])
+		logger.WithFields(log.Fields{
+			"server":          config.Host,
+			"server_override": server,
+		}).Debug(fmt.Sprintf("Server matches %s, overriding it", pattern))
+		if u, err := url.Parse(config.Host); err == nil && config.ServerName == "" && !config.Insecure {
+			config.ServerName = u.Hostname()
+		}
+		config.Host = server
+		return true
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+)
+
+// ErrNoSuitableServiceAccount is returned when none of the candidate service accounts exists with a token secret,
+// the tried names are logged and reported in the meta of the context
+var ErrNoSuitableServiceAccount = errors.New("None of the service accounts exists with a token secret")
+
+// selectServiceAccount sets options.serviceaccount to the first of the candidates that exists and has a token secret
+func selectServiceAccount(clientset kubeConfig.Interface, options *getOverContextOptions) error {
+	for _, name := range options.serviceaccounts {
+		sa, e := clientset.CoreV1().ServiceAccounts(options.namespace).Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(e) {
+			options.logger.Info(fmt.Sprintf("Service account %s not found", name))
+			continue
+		}
+		if e != nil {
+			return e
+		}
+		if len(sa.Secrets) == 0 {
+			options.logger.Info(fmt.Sprintf("Service account %s has no token secret", name))
+			continue
+		}
+		options.logger.Info(fmt.Sprintf("Using service account %s", name))
+		options.serviceaccount = name
+		return nil
+	}
+	tried := strings.Join(options.serviceaccounts, ",")
+	options.meta["serviceaccounts_tried"] = tried
+	options.logger.WithField("serviceaccounts", tried).Warn(ErrNoSuitableServiceAccount.Error())
+	return ErrNoSuitableServiceAccount
+}
+
+// checkAgentServiceAccount fails the context when the agent service account does not exist in the agent namespace,
+// the agent would not start with it. Without an agent namespace Codefresh picks one, so it can not be checked.
+func checkAgentServiceAccount(clientset kubeConfig.Interface, options *getOverContextOptions) error {
+	if options.agentNamespace == "" {
+		options.logger.Info("Agent namespace is not set, not checking the agent service account")
+		return nil
+	}
+	_, e := clientset.CoreV1().ServiceAccounts(options.agentNamespace).Get(options.agentServiceAccount, metav1.GetOptions{})
+	if apierrors.IsNotFound(e) {
+		message := fmt.Sprintf("Agent service account: %s not found in namespace: %s", options.agentServiceAccount, options.agentNamespace)
+		options.logger.Warn(message)
+		return errors.New(message)
+	}
+	if e != nil {
+		message := fmt.Sprintf("Failed to get agent service account with error:\n%s", e)
+		options.logger.Warn(message)
+		return e
+	}
+	return nil
+}
+
+// WithAutoCreateServiceAccount creates the service account, with a cluster role bound to it, when it does not exist.
+// The cluster role grants every verb on every resource and non-resource URL, the same as cluster-admin,
+// unless WithServiceAccountClusterRole is set.
+// With cleanupOnFailure the created objects are deleted when the context fails after they were created.
+func WithAutoCreateServiceAccount(autoCreate bool, cleanupOnFailure bool) Option {
+	return func(kube *kubernetes) {
+		kube.autoCreateSA = autoCreate
+		kube.cleanupOnFailure = cleanupOnFailure
+	}
+}
+
+// WithServiceAccountClusterRole binds the service accounts created by WithAutoCreateServiceAccount to an existing
+// cluster role, e.g. the built-in cluster-admin or edit, instead of creating a cluster role for each of them
+func WithServiceAccountClusterRole(role string) Option {
+	return func(kube *kubernetes) {
+		kube.saClusterRole = role
+	}
+}
+
+// serviceAccountRoleName is the name of the cluster role and the cluster role binding of the created service account
+func serviceAccountRoleName(namespace string, name string) string {
+	return fmt.Sprintf("codefresh-stevedore-%s-%s", namespace, name)
+}
+
+// createServiceAccount creates the service account and grants it full access to the cluster,
+// which is what Codefresh needs to run builds and deployments on it, or binds it to the cluster role
+// of WithServiceAccountClusterRole
+func createServiceAccount(clientset kubeConfig.Interface, options *getOverContextOptions) (*v1.ServiceAccount, error) {
+	roleName := serviceAccountRoleName(options.namespace, options.serviceaccount)
+	boundRole := roleName
+	if options.saClusterRole != "" {
+		boundRole = options.saClusterRole
+	}
+	options.logger.Info(fmt.Sprintf("Creating service account %s", options.serviceaccount))
+	sa, e := clientset.CoreV1().ServiceAccounts(options.namespace).Create(&v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      options.serviceaccount,
+			Namespace: options.namespace,
+		},
+	})
+	if e != nil {
+		return nil, e
+	}
+	options.createdSA = true
+	if options.saClusterRole == "" {
+		e = createServiceAccountRole(clientset, roleName, options)
+		if e != nil {
+			return sa, e
+		}
+	}
+	options.logger.Info(fmt.Sprintf("Creating cluster role binding %s of cluster role %s", roleName, boundRole))
+	_, e = clientset.RbacV1().ClusterRoleBindings().Create(&rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: roleName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     boundRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      options.serviceaccount,
+				Namespace: options.namespace,
+			},
+		},
+	})
+	if e != nil {
+		return sa, e
+	}
+	options.createdBinding = true
+	return sa, nil
+}
+
+// createServiceAccountRole creates the cluster role granting everything, the same as cluster-admin
+func createServiceAccountRole(clientset kubeConfig.Interface, roleName string, options *getOverContextOptions) error {
+	options.logger.Info(fmt.Sprintf("Creating cluster role %s", roleName))
+	_, e := clientset.RbacV1().ClusterRoles().Create(&rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: roleName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"*"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			},
+			{
+				NonResourceURLs: []string{"*"},
+				Verbs:           []string{"*"},
+			},
+		},
+	})
+	if e != nil {
+		return e
+	}
+	options.createdRole = true
+	return nil
+}
+
+// deleteServiceAccount removes what createServiceAccount created, the objects that already existed are kept
+// and missing objects are only logged
+func deleteServiceAccount(clientset kubeConfig.Interface, options *getOverContextOptions) {
+	roleName := serviceAccountRoleName(options.namespace, options.serviceaccount)
+	deletes := []struct {
+		kind    string
+		name    string
+		created bool
+		fn      func() error
+	}{
+		{"cluster role binding", roleName, options.createdBinding, func() error {
+			return clientset.RbacV1().ClusterRoleBindings().Delete(roleName, &metav1.DeleteOptions{})
+		}},
+		{"cluster role", roleName, options.createdRole, func() error {
+			return clientset.RbacV1().ClusterRoles().Delete(roleName, &metav1.DeleteOptions{})
+		}},
+		{"service account", options.serviceaccount, options.createdSA, func() error {
+			return clientset.CoreV1().ServiceAccounts(options.namespace).Delete(options.serviceaccount, &metav1.DeleteOptions{})
+		}},
+	}
+	for _, d := range deletes {
+		if !d.created {
+			continue
+		}
+		e := d.fn()
+		if e != nil {
+			options.logger.Warn(fmt.Sprintf("Failed to delete %s %s with error:\n%s", d.kind, d.name, e))
+			continue
+		}
+		options.logger.Info(fmt.Sprintf("Deleted %s %s", d.kind, d.name))
+	}
+}
@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// rbacServer answers the creations with the status of create and records the requests,
+// the bodies of the created cluster role bindings are kept in bindings
+type rbacServer struct {
+	create   int
+	mu       sync.Mutex
+	requests []string
+	bindings []rbacv1.ClusterRoleBinding
+}
+
+func (s *rbacServer) start(t *testing.T) (*httptest.Server, kubeConfig.Interface) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, req.Method+" "+req.URL.Path)
+		body, _ := ioutil.ReadAll(req.Body)
+		if strings.HasSuffix(req.URL.Path, "/clusterrolebindings") {
+			binding := rbacv1.ClusterRoleBinding{}
+			json.Unmarshal(body, &binding)
+			s.bindings = append(s.bindings, binding)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		status := http.StatusOK
+		if req.Method == "POST" {
+			status = s.create
+		}
+		w.WriteHeader(status)
+		if status >= 300 {
+			json.NewEncoder(w).Encode(&metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonAlreadyExists,
+				Code:     int32(status),
+			})
+			return
+		}
+		w.Write(body)
+	}))
+	clientset, err := kubeConfig.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, clientset
+}
+
+func TestCleanupKeepsServiceAccountCreatedByOthers(t *testing.T) {
+	s := &rbacServer{create: http.StatusConflict}
+	server, clientset := s.start(t)
+	defer server.Close()
+	options := testOptions()
+	options.namespace, options.serviceaccount = "default", "deployer"
+
+	_, err := createServiceAccount(clientset, options)
+	if err == nil {
+		t.Fatal("expected the creation to fail")
+	}
+	deleteServiceAccount(clientset, options)
+
+	for _, request := range s.requests {
+		if strings.HasPrefix(request, "DELETE") {
+			t.Errorf("expected nothing to be deleted, got %s", request)
+		}
+	}
+}
+
+func TestCreateServiceAccountBindsConfiguredClusterRole(t *testing.T) {
+	s := &rbacServer{create: http.StatusCreated}
+	server, clientset := s.start(t)
+	defer server.Close()
+	options := testOptions()
+	options.namespace, options.serviceaccount = "default", "deployer"
+	options.saClusterRole = "edit"
+
+	_, err := createServiceAccount(clientset, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteServiceAccount(clientset, options)
+
+	for _, request := range s.requests {
+		if strings.Contains(request, "/clusterroles") {
+			t.Errorf("expected no cluster role to be created or deleted, got %s", request)
+		}
+	}
+	if len(s.bindings) != 1 || s.bindings[0].RoleRef.Name != "edit" {
+		t.Errorf("expected a binding of cluster role edit, got %+v", s.bindings)
+	}
+	if !options.createdSA || options.createdRole || !options.createdBinding {
+		t.Errorf("expected the service account and the binding to be created, got %v %v %v", options.createdSA, options.createdRole, options.createdBinding)
+	}
+}
@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// WithSmokeTest runs the pipeline against every added cluster and reports it as VERIFIED or SMOKE_FAILED
+// instead of SUCCESS, timeout bounds a single run (0 means until the context deadline)
+func WithSmokeTest(pipeline string, timeout time.Duration) Option {
+	return func(kube *kubernetes) {
+		kube.smokeTestPipeline = pipeline
+		kube.smokeTestTimeout = timeout
+	}
+}
+
+// smokeTest runs the pipeline of the smoke test for the added cluster and returns the status to report,
+// its failure is only reported as the cluster is already in Codefresh and retrying would conflict
+func smokeTest(ctx context.Context, options *getOverContextOptions) (string, string) {
+	if options.smokeTestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.smokeTestTimeout)
+		defer cancel()
+	}
+	options.logger.Info(fmt.Sprintf("Running smoke test pipeline %s", options.smokeTestPipeline))
+	options.meta["smoke_test"] = options.smokeTestPipeline
+	err := options.codefresh.RunSmokeTest(ctx, codefresh.SmokeTestOptions{
+		Pipeline:    options.smokeTestPipeline,
+		ClusterName: options.name,
+	})
+	if err != nil {
+		message := fmt.Sprintf("Failed to run smoke test with error:\n%s", err)
+		options.logger.Error(message)
+		return reporter.SMOKE_FAILED, message
+	}
+	options.logger.Info("Smoke test passed")
+	return reporter.VERIFIED, ""
+}
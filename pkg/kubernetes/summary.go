@@ -0,0 +1,112 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// timings is the content of the timing cache, the time a single worker spends on a context on average
+type timings struct {
+	AverageContextSeconds float64 `json:"averageContextSeconds"`
+}
+
+// WithTimingCache keeps the average time spent on a context in the file, it is used to estimate
+// the duration of the next run in the summary logged before GoOverAllContexts starts
+func WithTimingCache(path string) Option {
+	return func(kube *kubernetes) {
+		kube.timingCache = path
+	}
+}
+
+// loadTimings returns nil when there is no cache or it can not be read
+func (kube *kubernetes) loadTimings() *timings {
+	if kube.timingCache == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(kube.timingCache)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to read timing cache with error:\n%s", err))
+		return nil
+	}
+	t := &timings{}
+	err = json.Unmarshal(data, t)
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to parse timing cache with error:\n%s", err))
+		return nil
+	}
+	return t
+}
+
+// saveTimings records the average of the run that took elapsed for n contexts
+func (kube *kubernetes) saveTimings(elapsed time.Duration, n int) {
+	if kube.timingCache == "" || n == 0 {
+		return
+	}
+	t := &timings{
+		AverageContextSeconds: elapsed.Seconds() * float64(kube.parallelism()) / float64(n),
+	}
+	data, _ := json.Marshal(t)
+	err := ioutil.WriteFile(kube.timingCache, data, 0600)
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to write timing cache with error:\n%s", err))
+	}
+}
+
+func (kube *kubernetes) parallelism() int {
+	if kube.workers > 1 {
+		return kube.workers
+	}
+	if kube.maxConcurrency > 1 {
+		return kube.maxConcurrency
+	}
+	return 1
+}
+
+// logRunSummary logs what GoOverAllContexts is about to do before the first context is processed
+func (kube *kubernetes) logRunSummary(names []string) {
+	excluded := 0
+	for _, name := range names {
+		if kube.isExcluded(name) {
+			excluded++
+		}
+	}
+	fields := log.Fields{
+		"run_id":            kube.runID,
+		"contexts_total":    len(names),
+		"contexts_excluded": excluded,
+		"workers":           kube.parallelism(),
+		"shuffle":           kube.shuffleContexts,
+		"overwrite":         kube.forceOverwrite,
+		"dry_run":           kube.dryRun,
+	}
+	filters := []string{}
+	if len(kube.includeProviders) > 0 {
+		filters = append(filters, "include-provider="+strings.Join(kube.includeProviders, ","))
+	}
+	if len(kube.excludeProviders) > 0 {
+		filters = append(filters, "exclude-provider="+strings.Join(kube.excludeProviders, ","))
+	}
+	if len(kube.excludeContexts) > 0 || len(kube.excludeContextPatterns) > 0 {
+		filters = append(filters, "exclude-context="+strings.Join(append(append([]string{}, kube.excludeContexts...), kube.excludeContextPatterns...), ","))
+	}
+	if len(filters) > 0 {
+		fields["filters"] = strings.Join(filters, " ")
+	}
+	if kube.runTimeout > 0 {
+		fields["run_deadline"] = kube.runTimeout.String()
+	}
+	if t := kube.loadTimings(); t != nil {
+		estimate := time.Duration(t.AverageContextSeconds * float64(len(names)-excluded) / float64(kube.parallelism()) * float64(time.Second))
+		fields["estimated_duration"] = estimate.Round(time.Second).String()
+	}
+	log.WithFields(fields).Info("Run summary")
+}
@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/codefresh"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+// RegistrationTarget is an integration the cluster is added to Codefresh as, scoped to a team and to namespaces
+type RegistrationTarget struct {
+	// Scope is the Codefresh team the integration is assigned to, it is appended to the cluster name
+	Scope      string   `json:"scope"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// WithRegistrationTargets adds every cluster once per target with the credentials extracted once,
+// as <name>-<scope>, each target is reported separately as <context>/<scope>.
+// Targets of the context overrides take precedence.
+func WithRegistrationTargets(targets []RegistrationTarget) Option {
+	return func(kube *kubernetes) {
+		kube.targets = targets
+	}
+}
+
+// targetsError is returned when some of the targets failed, they are already reported
+// and retrying the context would add the others again
+type targetsError struct {
+	failed []string
+	total  int
+}
+
+func (e *targetsError) Error() string {
+	return fmt.Sprintf("Failed to add %d of %d targets: %s", len(e.failed), e.total, strings.Join(e.failed, ", "))
+}
+
+// targetOptions are the create options and the entry of a target, the meta is a copy of the meta of the context
+func targetOptions(options *getOverContextOptions, base *codefresh.CreateOptions, target RegistrationTarget) (codefresh.CreateOptions, string, map[string]string) {
+	createOptions := *base
+	createOptions.Name = fmt.Sprintf("%s-%s", base.Name, target.Scope)
+	createOptions.TeamNames = append(append([]string{}, base.TeamNames...), target.Scope)
+	createOptions.Namespaces = target.Namespaces
+	meta := map[string]string{}
+	for k, v := range options.meta {
+		meta[k] = v
+	}
+	meta["codefresh_name"] = createOptions.Name
+	return createOptions, fmt.Sprintf("%s/%s", options.contextName, target.Scope), meta
+}
+
+// registerTargets creates an integration per target, the results are reported per target
+// so a failed target neither fails nor retries the others, a *targetsError is returned when any failed
+func registerTargets(ctx context.Context, options *getOverContextOptions, base *codefresh.CreateOptions) error {
+	failed := []string{}
+	for _, target := range options.targets {
+		createOptions, entryName, meta := targetOptions(options, base, target)
+		logger := options.logger.WithField("scope", target.Scope)
+		report := func(status string, message string) {
+			options.reporter.AddEntry(reporter.ReportEntry{
+				Name:    entryName,
+				Status:  status,
+				Message: message,
+				Meta:    meta,
+			})
+		}
+
+		fp := fingerprint(&createOptions, options.requestedToken)
+		if options.fingerprints != nil && options.fingerprints.Unchanged(createOptions.Name, fp) {
+			logger.Info("Cluster is unchanged since it was added, skipping")
+			report(reporter.UNCHANGED, "")
+			continue
+		}
+		logger.Info(fmt.Sprintf("Creating cluster %s in Codefresh", createOptions.Name))
+		result, e := codefresh.CreateOrUpdate(codefresh.WithLogger(ctx, logger), options.codefresh, &createOptions, options.forceOverwrite)
+		if e == codefresh.ErrConflict {
+			logger.Error(fmt.Sprintf("Cluster %s already exists in Codefresh, use --overwrite to replace it", createOptions.Name))
+			report(reporter.FAILED_CONFLICT, e.Error())
+			failed = append(failed, target.Scope)
+			continue
+		}
+		if e != nil {
+			message := fmt.Sprintf("Failed to add cluster with error:\n%s", e)
+			logger.Error(message)
+			report(reporter.FAILED, message)
+			failed = append(failed, target.Scope)
+			continue
+		}
+		if id := codefresh.ClusterID(result); id != "" {
+			meta["cluster_id"] = id
+		}
+		meta["registered_at"] = time.Now().UTC().Format(time.RFC3339)
+		logger.Info(fmt.Sprint("Cluster added!"))
+		report(reporter.SUCCESS, string(result))
+		if options.fingerprints != nil {
+			e = options.fingerprints.Save(createOptions.Name, fp, options.tokenExpiresAt)
+			if e != nil {
+				logger.Warn(fmt.Sprintf("Failed to save cluster fingerprint with error:\n%s", e))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return &targetsError{failed: failed, total: len(options.targets)}
+	}
+	return nil
+}
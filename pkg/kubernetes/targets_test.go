@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var testTargets = []RegistrationTarget{{Scope: "dev"}, {Scope: "prod"}}
+
+func TestRegisterTargetsReportsEachTargetOnce(t *testing.T) {
+	cf := &fakeCodefresh{fail: map[string]bool{"ctx-0-prod": true}}
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(testConfig(1), cf, rep,
+		WithCredentialExtractor(&fakeExtractor{}),
+		WithRegistrationTargets(testTargets),
+		WithRetries(2, 0),
+	)
+
+	kube.GoOverAllContexts()
+
+	statuses := map[string]string{}
+	for _, e := range rep.Entries() {
+		statuses[e.Name] = e.Status
+	}
+	if len(statuses) != 2 || statuses["ctx-0/dev"] != reporter.SUCCESS || statuses["ctx-0/prod"] != reporter.FAILED {
+		t.Errorf("expected one entry per target, got %v", statuses)
+	}
+	if len(cf.created) != 1 {
+		t.Errorf("expected the failed target not to retry the others, got %v", cf.created)
+	}
+	if teams := cf.teams["ctx-0-dev"]; len(teams) != 1 || teams[0] != "dev" {
+		t.Errorf("expected the target to be assigned to the team of its scope, got %v", cf.teams)
+	}
+}
+
+func TestRegisterTargetsReturnsTheFailedTargets(t *testing.T) {
+	cf := &fakeCodefresh{fail: map[string]bool{"ctx-0-dev": true, "ctx-0-prod": true}}
+	processor := &ContextProcessor{
+		ContextName: "ctx-0",
+		Namespace:   "default",
+		Config:      clientcmd.NewNonInteractiveClientConfig(*testConfig(1), "ctx-0", &clientcmd.ConfigOverrides{}, nil),
+		Codefresh:   cf,
+		Reporter:    reporter.NewReporter(),
+		Options: []Option{
+			WithCredentialExtractor(&fakeExtractor{}),
+			WithRegistrationTargets(testTargets),
+		},
+	}
+
+	err := processor.Process(context.Background())
+
+	e, ok := err.(*targetsError)
+	if !ok {
+		t.Fatalf("expected a targets error, got %v", err)
+	}
+	if len(e.failed) != 2 || e.total != 2 {
+		t.Errorf("expected both targets to fail, got %s", e)
+	}
+}
+
+func TestDryRunReportsEachTarget(t *testing.T) {
+	cf := &fakeCodefresh{}
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(testConfig(1), cf, rep,
+		WithCredentialExtractor(&fakeExtractor{}),
+		WithRegistrationTargets(testTargets),
+		WithDryRun(true),
+	)
+
+	kube.GoOverAllContexts()
+
+	entries := rep.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected one entry per target, got %d", len(entries))
+	}
+	for i, scope := range []string{"dev", "prod"} {
+		if entries[i].Name != "ctx-0/"+scope || entries[i].Status != reporter.DRY_RUN || entries[i].Meta["codefresh_name"] != "ctx-0-"+scope {
+			t.Errorf("unexpected entry %+v", entries[i])
+		}
+	}
+	if len(cf.created) != 0 {
+		t.Errorf("expected nothing to be created in a dry run, got %v", cf.created)
+	}
+}
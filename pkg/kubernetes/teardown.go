@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// DeleteAllOptions configures GoDeleteAllClusters
+	DeleteAllOptions struct {
+		// Interval is the minimal time between two deletions, so a fleet does not exhaust the rate limit of Codefresh
+		Interval time.Duration
+		// Retries is how many times a failed deletion is retried, waiting RetryDelay between the attempts
+		Retries    int
+		RetryDelay time.Duration
+		// Checkpoint records the deleted clusters, a run with the same file skips them, optional
+		Checkpoint *DeleteCheckpoint
+	}
+
+	// DeleteCheckpoint persists the clusters GoDeleteAllClusters deleted,
+	// so an interrupted run resumes where it stopped
+	DeleteCheckpoint struct {
+		path    string
+		mu      sync.Mutex
+		deleted map[string]string
+	}
+)
+
+// LoadDeleteCheckpoint reads the checkpoint file, a missing file is an empty checkpoint
+func LoadDeleteCheckpoint(path string) (*DeleteCheckpoint, error) {
+	checkpoint := &DeleteCheckpoint{
+		path:    path,
+		deleted: map[string]string{},
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &checkpoint.deleted)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Deleted returns true when the cluster was deleted by a previous run
+func (c *DeleteCheckpoint) Deleted(name string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.deleted[name]
+	return ok
+}
+
+// Save records the time the cluster was deleted at and writes the checkpoint file
+func (c *DeleteCheckpoint) Save(name string, at time.Time) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted[name] = at.UTC().Format(time.RFC3339)
+	data, err := json.MarshalIndent(c.deleted, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0600)
+}
+
+// GoDeleteAllClusters deletes the clusters the contexts of the kubeconfig are saved under from Codefresh,
+// one at a time and at most one per opt.Interval. Each cluster is reported as DELETED, FAILED,
+// or SKIPPED when it is not in Codefresh or was deleted by a previous run with the same checkpoint.
+func (kube *kubernetes) GoDeleteAllClusters(opt DeleteAllOptions) error {
+	ctx := context.Background()
+	clusters, err := kube.codefresh.List(ctx)
+	if err != nil {
+		return err
+	}
+	registered := map[string]bool{}
+	for _, cluster := range clusters {
+		registered[cluster.Selector] = true
+	}
+	names := []string{}
+	for name := range kube.expectedClusterNames() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var last time.Time
+	for _, name := range names {
+		logger := log.WithField("name", name)
+		if opt.Checkpoint.Deleted(name) {
+			kube.reporter.AddToReport(name, reporter.SKIPPED, "Deleted by a previous run")
+			continue
+		}
+		if !registered[name] {
+			kube.reporter.AddToReport(name, reporter.SKIPPED, "Not registered in Codefresh")
+			continue
+		}
+		if wait := opt.Interval - time.Since(last); !last.IsZero() && wait > 0 {
+			time.Sleep(wait)
+		}
+		last = time.Now()
+		err := kube.deleteWithRetries(ctx, logger, name, opt)
+		if err != nil {
+			message := fmt.Sprintf("Failed to delete cluster with error:\n%s", err)
+			logger.Error(message)
+			kube.reporter.AddToReport(name, reporter.FAILED, message)
+			continue
+		}
+		logger.Info("Cluster deleted!")
+		kube.reporter.AddToReport(name, reporter.DELETED, "")
+		err = opt.Checkpoint.Save(name, time.Now())
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to save checkpoint with error:\n%s", err))
+		}
+	}
+	return nil
+}
+
+func (kube *kubernetes) deleteWithRetries(ctx context.Context, logger *log.Entry, name string, opt DeleteAllOptions) error {
+	var err error
+	for attempt := 0; attempt <= opt.Retries; attempt++ {
+		if attempt > 0 {
+			logger.WithField("attempt", attempt).Warn(fmt.Sprintf("Retrying in %s", opt.RetryDelay))
+			time.Sleep(opt.RetryDelay)
+		}
+		err = kube.codefresh.Delete(ctx, name)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+// Package testing has helpers to inspect the calls stevedore makes to the clusters
+package testing
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+type (
+	// Exchange is a request made through RecordingTransport and its outcome,
+	// the bodies are read in full so the original request and response can still be consumed
+	Exchange struct {
+		Request      *http.Request
+		RequestBody  []byte
+		Response     *http.Response
+		ResponseBody []byte
+		Err          error
+	}
+
+	// RecordingTransport stores all the requests and responses that go through it, it is safe for concurrent use
+	RecordingTransport struct {
+		mu        sync.Mutex
+		exchanges []Exchange
+	}
+
+	recordingRoundTripper struct {
+		recorder *RecordingTransport
+		next     http.RoundTripper
+	}
+)
+
+// NewRecordingTransport returns an empty recorder, pass its Wrap to kubernetes.WithTransportWrapper
+func NewRecordingTransport() *RecordingTransport {
+	return &RecordingTransport{}
+}
+
+// Wrap records the requests made through next
+func (t *RecordingTransport) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingRoundTripper{
+		recorder: t,
+		next:     next,
+	}
+}
+
+// Exchanges returns a copy of the recorded exchanges, in the order the requests were made
+func (t *RecordingTransport) Exchanges() []Exchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Exchange{}, t.exchanges...)
+}
+
+// Reset drops the recorded exchanges
+func (t *RecordingTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exchanges = nil
+}
+
+func (t *RecordingTransport) record(exchange Exchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exchanges = append(t.exchanges, exchange)
+}
+
+func (rt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange := Exchange{
+		Request: req,
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		exchange.RequestBody = body
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	res, err := rt.next.RoundTrip(req)
+	if err != nil {
+		exchange.Err = err
+		rt.recorder.record(exchange)
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		exchange.Err = err
+		rt.recorder.record(exchange)
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	exchange.Response = res
+	exchange.ResponseBody = body
+	rt.recorder.record(exchange)
+	return res, nil
+}
@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// WithTokenExpiry sets the lifetime of the tokens requested for service accounts without a token secret,
+// 0 keeps the API server default
+func WithTokenExpiry(seconds int64) Option {
+	return func(kube *kubernetes) {
+		kube.tokenExpirySeconds = seconds
+	}
+}
+
+// tokenFromSecret reads the token and the CA from the secret of the service account,
+// when it references several secrets the token secret is found with a single list call
+func tokenFromSecret(clientset kubeConfig.Interface, sa *v1.ServiceAccount, options *getOverContextOptions) ([]byte, []byte, error) {
+	if len(sa.Secrets) > 1 {
+		return tokenFromSecrets(clientset, sa, options)
+	}
+	secretName := string(sa.Secrets[0].Name)
+	namespace := secretNamespace(sa.Namespace, sa.Secrets[0])
+	options.logger.WithFields(log.Fields{
+		"secret_name": secretName,
+		"namespace":   namespace,
+	}).Info(fmt.Sprint("Found service account accisiated with secret"))
+
+	options.logger.Info("Fetching secret from cluster")
+	secret, e := clientset.CoreV1().Secrets(namespace).Get(secretName, metav1.GetOptions{})
+	if e != nil {
+		message := fmt.Sprintf("Failed to get secrets with error:\n%s", e)
+		options.logger.Warn(message)
+		return nil, nil, e
+	}
+	options.logger.Info(fmt.Sprint("Found secret"))
+	return secret.Data["token"], secret.Data["ca.crt"], nil
+}
+
+// WithSecretFieldSelector narrows the list of the token secrets of service accounts referencing several secrets
+// further than by type, it is ANDed with type=kubernetes.io/service-account-token. nil selects by type only.
+func WithSecretFieldSelector(selector fields.Selector) Option {
+	return func(kube *kubernetes) {
+		kube.secretFieldSelector = selector
+	}
+}
+
+// secretListOptions selects the token secrets on the server, with the configured field selector on top
+func secretListOptions(options *getOverContextOptions) metav1.ListOptions {
+	selector := fields.OneTermEqualSelector("type", string(v1.SecretTypeServiceAccountToken))
+	if options.secretFieldSelector != nil && !options.secretFieldSelector.Empty() {
+		selector = fields.AndSelectors(selector, options.secretFieldSelector)
+	}
+	return metav1.ListOptions{
+		FieldSelector: selector.String(),
+	}
+}
+
+// ownedBy returns true when the token controller issued the secret for the service account,
+// the uid is compared only when both carry it, so a recreated service account does not take an old token
+func ownedBy(secret v1.Secret, sa *v1.ServiceAccount) bool {
+	if secret.Annotations[v1.ServiceAccountNameKey] != sa.Name {
+		return false
+	}
+	uid := secret.Annotations[v1.ServiceAccountUIDKey]
+	return uid == "" || sa.UID == "" || uid == string(sa.UID)
+}
+
+// tokenFromSecrets lists the token secrets of each namespace referenced by the service account and takes
+// the first referenced one. Field selectors have no set operator, so instead of
+// "metadata.name in (...)" the secrets are selected by type and filtered by name here.
+// When none of the referenced secrets is listed, the secret annotated with the service account is taken.
+func tokenFromSecrets(clientset kubeConfig.Interface, sa *v1.ServiceAccount, options *getOverContextOptions) ([]byte, []byte, error) {
+	byNamespace := map[string][]v1.Secret{}
+	namespaces := []string{}
+	for _, ref := range sa.Secrets {
+		namespace := secretNamespace(sa.Namespace, ref)
+		secrets, listed := byNamespace[namespace]
+		if !listed {
+			options.logger.WithField("namespace", namespace).Info("Listing token secrets from cluster")
+			list, e := clientset.CoreV1().Secrets(namespace).List(secretListOptions(options))
+			if e != nil {
+				message := fmt.Sprintf("Failed to list secrets with error:\n%s", e)
+				options.logger.Warn(message)
+				return nil, nil, e
+			}
+			secrets = list.Items
+			byNamespace[namespace] = secrets
+			namespaces = append(namespaces, namespace)
+		}
+		for _, secret := range secrets {
+			if secret.Name == ref.Name {
+				options.logger.WithFields(log.Fields{
+					"secret_name": secret.Name,
+					"namespace":   namespace,
+				}).Info(fmt.Sprint("Found secret"))
+				return secret.Data["token"], secret.Data["ca.crt"], nil
+			}
+		}
+	}
+	for _, namespace := range namespaces {
+		for _, secret := range byNamespace[namespace] {
+			if ownedBy(secret, sa) {
+				options.logger.WithFields(log.Fields{
+					"secret_name": secret.Name,
+					"namespace":   namespace,
+				}).Info(fmt.Sprint("Found secret annotated with the service account"))
+				return secret.Data["token"], secret.Data["ca.crt"], nil
+			}
+		}
+	}
+	message := fmt.Sprintf("None of the %d secrets of service account %s is a service account token", len(sa.Secrets), sa.Name)
+	options.logger.Warn(message)
+	return nil, nil, categorize(reporter.CategoryNoSecret, errors.New(message))
+}
+
+// tokenFromRequest requests a token for the service account with the TokenRequest API,
+// the CA is taken from the client config as there is no secret to read it from
+func tokenFromRequest(clientset kubeConfig.Interface, clientCnf *rest.Config, sa *v1.ServiceAccount, options *getOverContextOptions) ([]byte, []byte, error) {
+	tr := &authv1.TokenRequest{}
+	if options.tokenExpirySeconds > 0 {
+		tr.Spec.ExpirationSeconds = &options.tokenExpirySeconds
+	}
+	tr, e := clientset.CoreV1().ServiceAccounts(sa.Namespace).CreateToken(sa.Name, tr)
+	if e != nil {
+		message := fmt.Sprintf("Failed to request service account token with error:\n%s", e)
+		options.logger.Warn(message)
+		return nil, nil, e
+	}
+	options.logger.WithField("expiration", tr.Status.ExpirationTimestamp.String()).Debug("Token requested")
+	options.requestedToken = true
+	options.tokenExpiresAt = tr.Status.ExpirationTimestamp.Time
+	ca := clientCnf.TLSClientConfig.CAData
+	if len(ca) == 0 && clientCnf.TLSClientConfig.CAFile != "" {
+		ca, e = ioutil.ReadFile(clientCnf.TLSClientConfig.CAFile)
+		if e != nil {
+			message := fmt.Sprintf("Failed to read cluster CA with error:\n%s", e)
+			options.logger.Warn(message)
+			return nil, nil, e
+		}
+	}
+	return []byte(tr.Status.Token), ca, nil
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeConfig "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// apiServer serves the objects by request path and 404 for the rest,
+// the fake clientset of client-go is not vendored
+func apiServer(t *testing.T, objects map[string]interface{}) (*httptest.Server, kubeConfig.Interface) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		obj, ok := objects[req.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(&metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(obj)
+	}))
+	clientset, err := kubeConfig.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, clientset
+}
+
+func tokenSecret(namespace string, name string, token string) v1.Secret {
+	return v1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       v1.SecretTypeServiceAccountToken,
+		Data: map[string][]byte{
+			"token":  []byte(token),
+			"ca.crt": []byte("ca"),
+		},
+	}
+}
+
+func testOptions() *getOverContextOptions {
+	return &getOverContextOptions{
+		logger: log.NewEntry(log.New()),
+		meta:   map[string]string{},
+	}
+}
+
+func TestTokenFromSecretInAnotherNamespace(t *testing.T) {
+	secret := tokenSecret("secrets", "sa-token", "cross-namespace")
+	server, clientset := apiServer(t, map[string]interface{}{
+		"/api/v1/namespaces/secrets/secrets/sa-token": &secret,
+	})
+	defer server.Close()
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "sa", Namespace: "apps"},
+		Secrets:    []v1.ObjectReference{{Name: "sa-token", Namespace: "secrets"}},
+	}
+
+	token, ca, err := tokenFromSecret(clientset, sa, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(token, []byte("cross-namespace")) || !bytes.Equal(ca, []byte("ca")) {
+		t.Errorf("expected the token of the referenced namespace, got %q %q", token, ca)
+	}
+}
+
+func TestTokenFromSecretDefaultsToServiceAccountNamespace(t *testing.T) {
+	secret := tokenSecret("apps", "sa-token", "same-namespace")
+	server, clientset := apiServer(t, map[string]interface{}{
+		"/api/v1/namespaces/apps/secrets/sa-token": &secret,
+	})
+	defer server.Close()
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "sa", Namespace: "apps"},
+		Secrets:    []v1.ObjectReference{{Name: "sa-token"}},
+	}
+
+	token, _, err := tokenFromSecret(clientset, sa, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(token) != "same-namespace" {
+		t.Errorf("expected the token of the namespace of the service account, got %q", token)
+	}
+}
+
+func TestTokenFromSecretsListsTheReferencedNamespace(t *testing.T) {
+	list := &v1.SecretList{
+		TypeMeta: metav1.TypeMeta{Kind: "SecretList", APIVersion: "v1"},
+		Items:    []v1.Secret{tokenSecret("secrets", "sa-token", "listed")},
+	}
+	server, clientset := apiServer(t, map[string]interface{}{
+		"/api/v1/namespaces/secrets/secrets": list,
+	})
+	defer server.Close()
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "sa", Namespace: "apps"},
+		Secrets: []v1.ObjectReference{
+			{Name: "dockercfg", Namespace: "secrets"},
+			{Name: "sa-token", Namespace: "secrets"},
+		},
+	}
+
+	token, _, err := tokenFromSecret(clientset, sa, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(token) != "listed" {
+		t.Errorf("expected the listed token, got %q", token)
+	}
+}
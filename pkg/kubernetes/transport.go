@@ -0,0 +1,32 @@
+package kubernetes
+
+import (
+	"net/http"
+
+	"k8s.io/client-go/rest"
+)
+
+// TransportWrapper wraps the transport of the clients of the clusters, e.g. to capture the requests while debugging
+type TransportWrapper func(http.RoundTripper) http.RoundTripper
+
+// WithTransportWrapper wraps the transport of the client of each context, after the wrapping of the kubeconfig
+// (e.g. the token refresh of the in-cluster config), nil keeps the transport as is
+func WithTransportWrapper(wrapper TransportWrapper) Option {
+	return func(kube *kubernetes) {
+		kube.transportWrapper = wrapper
+	}
+}
+
+// applyTransportWrapper chains the wrapper after the WrapTransport the config already has
+func applyTransportWrapper(config *rest.Config, wrapper TransportWrapper) {
+	if wrapper == nil {
+		return
+	}
+	wrapped := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrapped != nil {
+			rt = wrapped(rt)
+		}
+		return wrapper(rt)
+	}
+}
@@ -0,0 +1,91 @@
+package kubernetes
+
+import (
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// WithStrictConfigValidation fails the contexts with problems found by ValidateConfig instead of only
+// warning about them, so they are not tried at all
+func WithStrictConfigValidation(strict bool) Option {
+	return func(kube *kubernetes) {
+		kube.strictConfigValidation = strict
+	}
+}
+
+// ValidateConfig returns the structural problems of the kubeconfig by context name, the ones clientcmd
+// loads without complaining and that otherwise fail the context deep in the run:
+// missing clusters and users, unreadable referenced files and inline data that is not PEM
+func ValidateConfig(config *api.Config) map[string][]string {
+	result := map[string][]string{}
+	for name, c := range config.Contexts {
+		if c == nil {
+			continue
+		}
+		problems := []string{}
+		if cluster, ok := config.Clusters[c.Cluster]; !ok || cluster == nil {
+			problems = append(problems, fmt.Sprintf("cluster %s is not found", c.Cluster))
+		} else {
+			problems = append(problems, checkFile("certificate-authority", cluster.CertificateAuthority)...)
+			problems = append(problems, checkPEM("certificate-authority-data", cluster.CertificateAuthorityData)...)
+		}
+		if user, ok := config.AuthInfos[c.AuthInfo]; !ok || user == nil {
+			problems = append(problems, fmt.Sprintf("user %s is not found", c.AuthInfo))
+		} else {
+			problems = append(problems, checkFile("client-certificate", user.ClientCertificate)...)
+			problems = append(problems, checkFile("client-key", user.ClientKey)...)
+			problems = append(problems, checkFile("tokenFile", user.TokenFile)...)
+			problems = append(problems, checkPEM("client-certificate-data", user.ClientCertificateData)...)
+			problems = append(problems, checkPEM("client-key-data", user.ClientKeyData)...)
+		}
+		if len(problems) > 0 {
+			result[name] = problems
+		}
+	}
+	return result
+}
+
+func checkFile(field string, path string) []string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return []string{fmt.Sprintf("%s %s is not readable: %s", field, path, err)}
+	}
+	f.Close()
+	return nil
+}
+
+func checkPEM(field string, data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	if block, _ := pem.Decode(data); block == nil {
+		return []string{fmt.Sprintf("%s is not a PEM block, check its base64 encoding", field)}
+	}
+	return nil
+}
+
+// validateConfig logs the problems of the kubeconfig before any context is processed and keeps them
+// for goOverContextInConfig to fail those contexts in strict mode
+func (kube *kubernetes) validateConfig(config *api.Config) {
+	problems := ValidateConfig(config)
+	names := []string{}
+	for name := range problems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.WithFields(log.Fields{
+			"context_name": name,
+		}).Warn(fmt.Sprintf("Kubeconfig entries of context %s are invalid: %s", name, strings.Join(problems[name], "; ")))
+	}
+	kube.configProblems = problems
+}
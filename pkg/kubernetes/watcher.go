@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// ConfigWatcher reloads the kubeconfig of the kubernetes API when the file is written.
+// fsnotify is not part of the vendored dependencies, so the file is polled
+// for modification time and size changes every Interval. It also catches the files
+// replaced by a rename, e.g. mounted secrets, that an inotify watch on the old inode misses.
+type ConfigWatcher struct {
+	Path     string
+	Interval time.Duration
+	// OnReload is called after the new config replaced the old one, optional
+	OnReload func(newConfig, oldConfig *api.Config)
+
+	kube *kubernetes
+}
+
+// NewConfigWatcher creates a watcher that updates the config of the given kubernetes API
+func NewConfigWatcher(kubeAPI API, path string) (*ConfigWatcher, error) {
+	kube, ok := kubeAPI.(*kubernetes)
+	if !ok {
+		return nil, errors.New("ConfigWatcher supports only the API created by NewKubernetesAPI")
+	}
+	return &ConfigWatcher{
+		Path:     path,
+		Interval: defaultWatchInterval,
+		kube:     kube,
+	}, nil
+}
+
+// Start watches the file in the background until the context is cancelled
+func (w *ConfigWatcher) Start(ctx context.Context) error {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return err
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		modTime, size := info.ModTime(), info.Size()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(w.Path)
+			if err != nil {
+				continue
+			}
+			if info.ModTime().Equal(modTime) && info.Size() == size {
+				continue
+			}
+			modTime, size = info.ModTime(), info.Size()
+			w.reload()
+		}
+	}()
+	return nil
+}
+
+func (w *ConfigWatcher) reload() {
+	logger := log.WithField("path", w.Path)
+	newConfig, err := clientcmd.LoadFromFile(w.Path)
+	if err != nil {
+		// the file may be in the middle of a write, the next change will trigger another reload
+		logger.Warn(fmt.Sprintf("Failed to reload kubeconfig with error:\n%s", err))
+		return
+	}
+	w.kube.configMu.Lock()
+	oldConfig := w.kube.config
+	w.kube.config = newConfig
+	w.kube.configMu.Unlock()
+	logger.Info("Kubeconfig reloaded")
+	if w.OnReload != nil {
+		w.OnReload(newConfig, oldConfig)
+	}
+}
+
+func (kube *kubernetes) getConfig() *api.Config {
+	kube.configMu.RLock()
+	defer kube.configMu.RUnlock()
+	return kube.config
+}
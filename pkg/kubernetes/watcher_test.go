@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const watcherTestConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:1
+users:
+- name: user
+  user:
+    token: token
+contexts:
+- name: ctx-0
+  context:
+    cluster: cluster
+    user: user
+`
+
+const watcherTestContext = `- name: ctx-1
+  context:
+    cluster: cluster
+    user: user
+`
+
+func TestConfigWatcherReloadsRewrittenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stevedore-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(watcherTestConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kubeAPI, err := NewKubernetesAPIFromFile(path, &fakeCodefresh{}, reporter.NewReporter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := NewConfigWatcher(kubeAPI, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.Interval = 10 * time.Millisecond
+	reloaded := make(chan [2]*api.Config, 1)
+	watcher.OnReload = func(newConfig, oldConfig *api.Config) {
+		reloaded <- [2]*api.Config{newConfig, oldConfig}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := watcher.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(watcherTestConfig+watcherTestContext), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case configs := <-reloaded:
+		if _, ok := configs[0].Contexts["ctx-1"]; !ok {
+			t.Errorf("expected the new config to have the added context, got %v", configs[0].Contexts)
+		}
+		if _, ok := configs[1].Contexts["ctx-1"]; ok {
+			t.Error("expected the old config to be the one loaded before the rewrite")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the callback to be called after the file was rewritten")
+	}
+	if _, ok := kubeAPI.(*kubernetes).getConfig().Contexts["ctx-1"]; !ok {
+		t.Error("expected the kubernetes API to use the new config")
+	}
+}
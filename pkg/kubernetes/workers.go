@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithWorkers processes the contexts of GoOverAllContexts by n workers in parallel.
+// The contexts are sorted by name and context i is always processed by worker i mod n,
+// so the same kubeconfig gives the same assignment and order on every run.
+func WithWorkers(n int) Option {
+	return func(kube *kubernetes) {
+		kube.workers = n
+	}
+}
+
+// DefaultMaxConcurrency is the number of contexts GoOverAllContexts processes in parallel unless WithMaxConcurrency is set,
+// the reporter passed to NewKubernetesAPI must be safe for concurrent use
+const DefaultMaxConcurrency = 10
+
+// WithMaxConcurrency processes up to n contexts of GoOverAllContexts in parallel, e.g. capped to not hammer
+// the Codefresh API, default is DefaultMaxConcurrency, 0 or 1 processes them one at a time. The contexts are taken by a pool of goroutines, each
+// taking the next context as soon as it is done with the previous one. WithWorkers of more than 1 takes precedence over it.
+func WithMaxConcurrency(n int) Option {
+	return func(kube *kubernetes) {
+		kube.maxConcurrency = n
+	}
+}
+
+// WithConcurrency processes the contexts of GoOverAllContexts by a pool of n goroutines.
+//
+// Deprecated: use WithMaxConcurrency, WithConcurrency is an alias of it.
+func WithConcurrency(n int) Option {
+	return WithMaxConcurrency(n)
+}
+
+// runShards calls fn for each of the names with the index of the worker processing it,
+// by the fixed workers when more than one is set, otherwise by the pool bounded by the max concurrency
+func (kube *kubernetes) runShards(names []string, fn func(int, string)) {
+	if kube.workers <= 1 && kube.maxConcurrency > 1 {
+		kube.runPool(names, fn)
+		return
+	}
+	if kube.workers <= 1 {
+		for _, name := range names {
+			fn(0, name)
+		}
+		return
+	}
+	shards := make([][]string, kube.workers)
+	for i, name := range names {
+		shards[i%kube.workers] = append(shards[i%kube.workers], name)
+	}
+	var wg sync.WaitGroup
+	for worker, shard := range shards {
+		wg.Add(1)
+		go func(worker int, shard []string) {
+			defer wg.Done()
+			for _, name := range shard {
+				fn(worker, name)
+			}
+		}(worker, shard)
+	}
+	wg.Wait()
+}
+
+// runPool calls fn for each of the names in the order of the names by at most kube.maxConcurrency goroutines,
+// the semaphore holds the indexes of the free workers, so the index is unique among the running calls
+func (kube *kubernetes) runPool(names []string, fn func(int, string)) {
+	free := make(chan int, kube.maxConcurrency)
+	for worker := 0; worker < kube.maxConcurrency; worker++ {
+		free <- worker
+	}
+	var wg sync.WaitGroup
+	for _, name := range names {
+		worker := <-free
+		wg.Add(1)
+		go func(worker int, name string) {
+			defer func() {
+				free <- worker
+				wg.Done()
+			}()
+			fn(worker, name)
+		}(worker, name)
+	}
+	wg.Wait()
+}
+
+// WithShuffle randomizes the order GoOverAllContexts processes the contexts in, so under a run deadline
+// the same contexts are not always the ones left out. A seed of 0 uses the current time.
+func WithShuffle(shuffle bool, seed int64) Option {
+	return func(kube *kubernetes) {
+		kube.shuffleContexts = shuffle
+		kube.shuffleSeed = seed
+	}
+}
+
+// shuffle reorders the sorted names in place when shuffling is on, the same seed gives the same order
+func (kube *kubernetes) shuffle(names []string) {
+	if !kube.shuffleContexts {
+		return
+	}
+	seed := kube.shuffleSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	// the seed is logged so an order dependent failure can be reproduced with --shuffle-seed
+	log.WithField("seed", seed).Info("Shuffling contexts")
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(names), func(i, j int) {
+		names[i], names[j] = names[j], names[i]
+	})
+}
@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+func TestMaxConcurrencyCapsParallelContexts(t *testing.T) {
+	cf := &fakeCodefresh{delay: 20 * time.Millisecond}
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(testConfig(12), cf, rep,
+		WithCredentialExtractor(&fakeExtractor{}),
+		WithMaxConcurrency(3),
+	)
+
+	kube.GoOverAllContexts()
+
+	if len(cf.created) != 12 {
+		t.Fatalf("expected 12 clusters, got %d", len(cf.created))
+	}
+	if cf.maxSeen > 3 {
+		t.Errorf("expected at most 3 contexts in parallel, got %d", cf.maxSeen)
+	}
+	if cf.maxSeen < 2 {
+		t.Errorf("expected the contexts to be processed in parallel, got %d at a time", cf.maxSeen)
+	}
+}
+
+func TestDefaultMaxConcurrency(t *testing.T) {
+	cf := &fakeCodefresh{delay: 20 * time.Millisecond}
+	kube := NewKubernetesAPIFromConfig(testConfig(15), cf, reporter.NewReporter(),
+		WithCredentialExtractor(&fakeExtractor{}),
+	)
+
+	kube.GoOverAllContexts()
+
+	if cf.maxSeen > DefaultMaxConcurrency || cf.maxSeen < 2 {
+		t.Errorf("expected up to %d contexts in parallel without WithMaxConcurrency, got %d", DefaultMaxConcurrency, cf.maxSeen)
+	}
+}
+
+func TestMaxConcurrencyOfOneIsSequential(t *testing.T) {
+	cf := &fakeCodefresh{delay: 5 * time.Millisecond}
+	kube := NewKubernetesAPIFromConfig(testConfig(4), cf, reporter.NewReporter(),
+		WithCredentialExtractor(&fakeExtractor{}),
+		WithMaxConcurrency(1),
+	)
+
+	kube.GoOverAllContexts()
+
+	if cf.maxSeen != 1 {
+		t.Errorf("expected one context at a time, got %d", cf.maxSeen)
+	}
+}
+
+func TestPoolReportsAllContextsWhenOneFails(t *testing.T) {
+	cf := &fakeCodefresh{delay: 5 * time.Millisecond}
+	rep := reporter.NewReporter()
+	kube := NewKubernetesAPIFromConfig(testConfig(10), cf, rep,
+		WithCredentialExtractor(&fakeExtractor{fail: map[string]bool{"ctx-0": true}}),
+		WithConcurrency(4),
+	)
+
+	kube.GoOverAllContexts()
+
+	entries := rep.Entries()
+	if len(entries) != 10 {
+		t.Fatalf("expected all 10 contexts to be reported, got %d", len(entries))
+	}
+	statuses := map[string]string{}
+	for _, e := range entries {
+		statuses[e.Name] = e.Status
+	}
+	if statuses["ctx-0"] != reporter.FAILED {
+		t.Errorf("expected ctx-0 to fail, got %s", statuses["ctx-0"])
+	}
+	for name, status := range statuses {
+		if name != "ctx-0" && status != reporter.SUCCESS {
+			t.Errorf("expected %s to be added, got %s", name, status)
+		}
+	}
+	if len(cf.created) != 9 {
+		t.Errorf("expected the other 9 contexts to be added, got %d", len(cf.created))
+	}
+}
+
+func TestRunPoolWorkerIndexesAreUnique(t *testing.T) {
+	kube := &kubernetes{maxConcurrency: 3}
+	names := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var mu sync.Mutex
+	running := map[int]bool{}
+	seen := 0
+	kube.runPool(names, func(worker int, name string) {
+		mu.Lock()
+		if running[worker] {
+			t.Errorf("worker %d is already running", worker)
+		}
+		if worker < 0 || worker >= 3 {
+			t.Errorf("worker index %d out of range", worker)
+		}
+		running[worker] = true
+		seen++
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		running[worker] = false
+		mu.Unlock()
+	})
+	if seen != len(names) {
+		t.Errorf("expected %d calls, got %d", len(names), seen)
+	}
+}
@@ -0,0 +1,127 @@
+package reporter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+)
+
+type (
+	// StatusChange is a context that is in both reports with different statuses
+	StatusChange struct {
+		Name     string `json:"name"`
+		Previous string `json:"previous"`
+		Current  string `json:"current"`
+	}
+
+	// ReportDiff is what changed between two runs, each list is sorted by context name
+	ReportDiff struct {
+		// NewlyFailing contexts failed in the current run but did not in the previous one
+		NewlyFailing []StatusChange `json:"newlyFailing"`
+		// NewlySucceeding contexts succeeded in the current run but did not in the previous one
+		NewlySucceeding []StatusChange `json:"newlySucceeding"`
+		// Changed contexts changed the status otherwise, e.g. from SUCCESS to UNCHANGED
+		Changed []StatusChange `json:"changed"`
+		// Appeared contexts are only in the current report and Disappeared only in the previous one
+		Appeared    []ReportEntry `json:"appeared"`
+		Disappeared []ReportEntry `json:"disappeared"`
+	}
+)
+
+// LoadJSONReport reads the entries of a report written by PrintJSON
+func LoadJSONReport(path string) ([]ReportEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &jsonReport{}
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		return nil, err
+	}
+	return report.Entries, nil
+}
+
+// DiffReportFiles compares two reports written by PrintJSON
+func DiffReportFiles(previousPath string, currentPath string) (*ReportDiff, error) {
+	previous, err := LoadJSONReport(previousPath)
+	if err != nil {
+		return nil, err
+	}
+	current, err := LoadJSONReport(currentPath)
+	if err != nil {
+		return nil, err
+	}
+	return DiffReports(previous, current), nil
+}
+
+// DiffReports compares the entries of two runs by context name
+func DiffReports(previous []ReportEntry, current []ReportEntry) *ReportDiff {
+	diff := &ReportDiff{
+		NewlyFailing:    []StatusChange{},
+		NewlySucceeding: []StatusChange{},
+		Changed:         []StatusChange{},
+		Appeared:        []ReportEntry{},
+		Disappeared:     []ReportEntry{},
+	}
+	before := map[string]ReportEntry{}
+	for _, entry := range previous {
+		before[entry.Name] = entry
+	}
+	after := map[string]ReportEntry{}
+	for _, entry := range current {
+		after[entry.Name] = entry
+	}
+	for _, entry := range previous {
+		if _, ok := after[entry.Name]; !ok {
+			diff.Disappeared = append(diff.Disappeared, entry)
+		}
+	}
+	for _, entry := range current {
+		old, ok := before[entry.Name]
+		if !ok {
+			diff.Appeared = append(diff.Appeared, entry)
+			continue
+		}
+		if old.Status == entry.Status {
+			continue
+		}
+		change := StatusChange{
+			Name:     entry.Name,
+			Previous: old.Status,
+			Current:  entry.Status,
+		}
+		switch {
+		case OnlyFailed(entry) && !OnlyFailed(old):
+			diff.NewlyFailing = append(diff.NewlyFailing, change)
+		case OnlySucceeded(entry) && !OnlySucceeded(old):
+			diff.NewlySucceeding = append(diff.NewlySucceeding, change)
+		default:
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for _, changes := range [][]StatusChange{diff.NewlyFailing, diff.NewlySucceeding, diff.Changed} {
+		sortChanges(changes)
+	}
+	for _, entries := range [][]ReportEntry{diff.Appeared, diff.Disappeared} {
+		sortEntries(entries)
+	}
+	return diff
+}
+
+// Regressed returns true when any context started failing
+func (d *ReportDiff) Regressed() bool {
+	return len(d.NewlyFailing) > 0
+}
+
+func sortChanges(changes []StatusChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+func sortEntries(entries []ReportEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+}
@@ -0,0 +1,167 @@
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+type (
+	// EmailReporterOptions configures the SMTP server and the message of the digest
+	EmailReporterOptions struct {
+		SMTPHost string
+		SMTPPort string
+		From     string
+		// To is a comma separated list of recipients
+		To string
+		// Subject is followed by the number of contexts and failures
+		Subject string
+		// TLSEnabled connects with TLS right away (e.g. port 465), otherwise STARTTLS is used when the server offers it
+		TLSEnabled bool
+		// Username and Password authenticate with PlainAuth when both are set
+		Username string
+		Password string
+		// Base collects the entries, so it still prints them with its own options, optional
+		Base reporter.Reporter
+	}
+
+	emailReporter struct {
+		reporter.Reporter
+		opts EmailReporterOptions
+	}
+)
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<html>
+<body>
+<p>{{.Summary.Succeeded}} succeeded, {{.Summary.Failed}} failed, {{.Summary.Skipped}} skipped of {{.Summary.Total}} contexts</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Context</th><th>Status</th><th>Registered</th><th>Message</th></tr>
+{{range .Entries}}<tr><td>{{.Name}}</td><td>{{.Status}}</td><td>{{index .Meta "registered_at"}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// NewEmailReporter buffers the entries and sends a digest of the run with an HTML table of the results on Flush
+func NewEmailReporter(opts EmailReporterOptions) reporter.Reporter {
+	base := opts.Base
+	if base == nil {
+		base = reporter.NewReporter()
+	}
+	return &emailReporter{
+		Reporter: base,
+		opts:     opts,
+	}
+}
+
+// Flush sends the digest of the run
+func (r *emailReporter) Flush() error {
+	err := r.Reporter.Flush()
+	if err != nil {
+		return err
+	}
+	msg, err := r.message()
+	if err != nil {
+		return err
+	}
+	return r.send(msg)
+}
+
+func (r *emailReporter) recipients() []string {
+	to := []string{}
+	for _, addr := range strings.Split(r.opts.To, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			to = append(to, addr)
+		}
+	}
+	return to
+}
+
+func (r *emailReporter) message() ([]byte, error) {
+	summary := r.Summary()
+	body := &bytes.Buffer{}
+	err := digestTemplate.Execute(body, struct {
+		Summary reporter.Summary
+		Entries []reporter.ReportEntry
+	}{summary, r.PrintableEntries()})
+	if err != nil {
+		return nil, err
+	}
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", r.opts.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(r.recipients(), ", "))
+	fmt.Fprintf(msg, "Subject: %s: %d contexts, %d failed\r\n", r.opts.Subject, summary.Total, summary.Failed)
+	fmt.Fprint(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprint(msg, "Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func (r *emailReporter) send(msg []byte) error {
+	addr := net.JoinHostPort(r.opts.SMTPHost, r.opts.SMTPPort)
+	tlsConfig := &tls.Config{
+		ServerName: r.opts.SMTPHost,
+	}
+	var client *smtp.Client
+	if r.opts.TLSEnabled {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return err
+		}
+		client, err = smtp.NewClient(conn, r.opts.SMTPHost)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		var err error
+		client, err = smtp.Dial(addr)
+		if err != nil {
+			return err
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			err = client.StartTLS(tlsConfig)
+			if err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	defer client.Close()
+	if r.opts.Username != "" && r.opts.Password != "" {
+		err := client.Auth(smtp.PlainAuth("", r.opts.Username, r.opts.Password, r.opts.SMTPHost))
+		if err != nil {
+			return fmt.Errorf("Failed to authenticate to SMTP server with error:\n%s", err)
+		}
+	}
+	err := client.Mail(r.opts.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range r.recipients() {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
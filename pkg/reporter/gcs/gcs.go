@@ -0,0 +1,126 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// DefaultObjectPath is the prefix of the report objects when the object path is empty
+	DefaultObjectPath = "stevedore-reports"
+
+	uploadURL = "https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s"
+	scope     = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+type (
+	gcsReporter struct {
+		reporter.Reporter
+		bucketName  string
+		objectPath  string
+		credentials io.Reader
+		runID       string
+		started     time.Time
+	}
+
+	// Option configures optional behaviour of the GCS reporter
+	Option func(*gcsReporter)
+)
+
+// WithBase collects the entries in the given reporter, so it still prints them with its own options
+func WithBase(base reporter.Reporter) Option {
+	return func(r *gcsReporter) {
+		r.Reporter = base
+	}
+}
+
+// WithRunID names the object after the id of the run, so it can be correlated with the logs of the run
+func WithRunID(id string) Option {
+	return func(r *gcsReporter) {
+		r.runID = id
+	}
+}
+
+// NewGCSReporter uploads the JSON report to <objectPath>/<run start time>-<run id>.json in the bucket on Flush.
+// credentials is the JSON key of a service account, Application Default Credentials are used when nil.
+func NewGCSReporter(bucketName string, objectPath string, credentials io.Reader, opts ...Option) reporter.Reporter {
+	if objectPath == "" {
+		objectPath = DefaultObjectPath
+	}
+	r := &gcsReporter{
+		Reporter:    reporter.NewReporter(),
+		bucketName:  bucketName,
+		objectPath:  objectPath,
+		credentials: credentials,
+		started:     time.Now(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// objectName is the name of the report object of the run
+func (r *gcsReporter) objectName() string {
+	return path.Join(r.objectPath, fmt.Sprintf("%s-%s.json", r.started.UTC().Format(time.RFC3339), r.runID))
+}
+
+// newHTTPClient authenticates the requests with the service account key or the Application Default Credentials
+func (r *gcsReporter) newHTTPClient(ctx context.Context) (*http.Client, error) {
+	if r.credentials == nil {
+		return google.DefaultClient(ctx, scope)
+	}
+	key, err := ioutil.ReadAll(r.credentials)
+	if err != nil {
+		return nil, err
+	}
+	config, err := google.JWTConfigFromJSON(key, scope)
+	if err != nil {
+		return nil, err
+	}
+	return config.Client(ctx), nil
+}
+
+// Flush uploads the report of the run to GCS
+func (r *gcsReporter) Flush() error {
+	err := r.Reporter.Flush()
+	if err != nil {
+		return err
+	}
+	body := &bytes.Buffer{}
+	err = r.PrintJSON(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client, err := r.newHTTPClient(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to authenticate to GCS with error:\n%s", err)
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf(uploadURL, url.PathEscape(r.bucketName), url.QueryEscape(r.objectName())), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("Failed to upload report to gs://%s/%s, status %d: %s", r.bucketName, r.objectName(), res.StatusCode, data)
+	}
+	return nil
+}
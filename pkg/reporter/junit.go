@@ -0,0 +1,75 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type (
+	junitTestSuite struct {
+		XMLName   xml.Name        `xml:"testsuite"`
+		Name      string          `xml:"name,attr"`
+		Tests     int             `xml:"tests,attr"`
+		Failures  int             `xml:"failures,attr"`
+		Skipped   int             `xml:"skipped,attr"`
+		TestCases []junitTestCase `xml:"testcase"`
+	}
+
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Failure   *junitMessage `xml:"failure,omitempty"`
+		Skipped   *junitMessage `xml:"skipped,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+
+	junitMessage struct {
+		Message string `xml:"message,attr,omitempty"`
+		Type    string `xml:"type,attr,omitempty"`
+		Body    string `xml:",chardata"`
+	}
+)
+
+// PrintJUnit writes the printed entries as a JUnit XML test suite, each context is a test case,
+// failed contexts carry a failure with the error message and skipped ones a skipped element
+func (r *reporter) PrintJUnit(w io.Writer) error {
+	suite := &junitTestSuite{
+		Name: "stevedore",
+	}
+	for _, d := range r.PrintableEntries() {
+		tc := junitTestCase{
+			Name:      d.Name,
+			ClassName: "stevedore",
+			SystemOut: strings.TrimSpace(formatMeta(d.Meta)),
+		}
+		switch {
+		case IsFailure(d.Status):
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: d.Status,
+				Type:    d.Status,
+				Body:    d.Message,
+			}
+		case OnlySkipped(d):
+			suite.Skipped++
+			tc.Skipped = &junitMessage{
+				Message: d.Message,
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suite)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,85 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Formats of the printed report
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// Output is a destination the report is printed to in its own format
+type Output struct {
+	Format string
+	// Path is the file to write, "-" is stdout
+	Path string
+}
+
+// ParseOutput parses <format>=<path>, e.g. json=report.json or text=-
+func ParseOutput(value string) (Output, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Output{}, fmt.Errorf("Output must be in the form of <format>=<path>, got %s", value)
+	}
+	output := Output{
+		Format: parts[0],
+		Path:   parts[1],
+	}
+	switch output.Format {
+	case FormatText, FormatJSON, FormatJUnit:
+		return output, nil
+	default:
+		return Output{}, fmt.Errorf("Unknown output format %s", output.Format)
+	}
+}
+
+// PrintTo writes the report in the format to w
+func PrintTo(r Reporter, format string, w io.Writer) error {
+	switch format {
+	case FormatText:
+		return r.PrintText(w)
+	case FormatJSON:
+		return r.PrintJSON(w)
+	case FormatJUnit:
+		return r.PrintJUnit(w)
+	default:
+		return fmt.Errorf("Unknown output format %s", format)
+	}
+}
+
+// PrintToOutputs writes the report to every output, all of them are tried and the first error is returned
+func PrintToOutputs(r Reporter, outputs []Output) error {
+	var first error
+	for _, output := range outputs {
+		err := printToOutput(r, output)
+		if err != nil {
+			err = fmt.Errorf("Failed to write %s report to %s with error:\n%s", output.Format, output.Path, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func printToOutput(r Reporter, output Output) error {
+	if output.Path == "-" {
+		return PrintTo(r, output.Format, os.Stdout)
+	}
+	f, err := os.Create(output.Path)
+	if err != nil {
+		return err
+	}
+	err = PrintTo(r, output.Format, f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	progressBarWidth    = 30
+	progressRenderEvery = 100 * time.Millisecond
+)
+
+// ProgressBarReporter renders a progress bar of the run in place, it forwards the entries to the base reporter.
+// The bar is disabled when the output is not a terminal.
+type ProgressBarReporter struct {
+	Reporter
+	mu         sync.Mutex
+	out        io.Writer
+	enabled    bool
+	total      int
+	done       int
+	started    time.Time
+	lastRender time.Time
+}
+
+// NewProgressBarReporter renders to out, usually os.Stdout
+func NewProgressBarReporter(base Reporter, out *os.File) *ProgressBarReporter {
+	return &ProgressBarReporter{
+		Reporter: base,
+		out:      out,
+		enabled:  terminal.IsTerminal(int(out.Fd())),
+		started:  time.Now(),
+	}
+}
+
+// SetTotal sets the number of contexts about to be processed, it must be called before the first entry is added
+func (p *ProgressBarReporter) SetTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = n
+	p.started = time.Now()
+	p.render(true)
+}
+
+func (p *ProgressBarReporter) AddToReport(contextName string, status string, message string) {
+	p.AddEntry(ReportEntry{
+		Name:    contextName,
+		Status:  status,
+		Message: message,
+	})
+}
+
+func (p *ProgressBarReporter) AddEntry(entry ReportEntry) {
+	p.Reporter.AddEntry(entry)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.render(p.done == p.total)
+}
+
+// Flush finishes the bar, so the report is printed below it
+func (p *ProgressBarReporter) Flush() error {
+	p.mu.Lock()
+	p.render(true)
+	if p.enabled && p.total > 0 {
+		fmt.Fprintln(p.out)
+	}
+	p.mu.Unlock()
+	return p.Reporter.Flush()
+}
+
+// render redraws the bar at most every progressRenderEvery unless forced, the caller holds the lock
+func (p *ProgressBarReporter) render(force bool) {
+	if !p.enabled || p.total == 0 {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(p.lastRender) < progressRenderEvery {
+		return
+	}
+	p.lastRender = now
+	done := p.done
+	if done > p.total {
+		done = p.total
+	}
+	filled := progressBarWidth * done / p.total
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	elapsed := now.Sub(p.started)
+	eta := "?"
+	if done > 0 {
+		remaining := time.Duration(int64(elapsed) / int64(done) * int64(p.total-done))
+		eta = remaining.Round(time.Second).String()
+	}
+	// \r returns to the start of the line and \x1b[K clears the rest of it
+	fmt.Fprintf(p.out, "\r[%s] %d/%d elapsed %s remaining %s\x1b[K", bar, done, p.total, elapsed.Round(time.Second), eta)
+}
@@ -1,53 +1,449 @@
 package reporter
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
 
 const (
-	SUCCESS = "SUCCESS"
-	FAILED  = "FAILED"
+	SUCCESS           = "SUCCESS"
+	FAILED            = "FAILED"
+	DEADLINE_EXCEEDED = "DEADLINE_EXCEEDED"
+	SKIPPED           = "SKIPPED"
+	SKIPPED_EXCLUDED  = "SKIPPED_EXCLUDED"
+	UNCHANGED         = "UNCHANGED"
+	FAILED_CONFLICT   = "FAILED_CONFLICT"
+	ORPHANED          = "ORPHANED"
+	HEALTHY           = "HEALTHY"
+	UNHEALTHY         = "UNHEALTHY"
+	VERIFIED          = "VERIFIED"
+	SMOKE_FAILED      = "SMOKE_FAILED"
+	REFRESHED         = "REFRESHED"
+	INVALID_NAME      = "INVALID_NAME"
+	RUN_FAILED        = "RUN_FAILED"
+	DELETED           = "DELETED"
+	RUNNER_CONNECTED  = "RUNNER_CONNECTED"
+	DRY_RUN           = "DRY_RUN"
+	RUNNER_PENDING    = "RUNNER_PENDING"
+)
+
+// Categories of the failures in the breakdown of the summary
+const (
+	CategoryConnectivity     = "connectivity"
+	CategoryRBAC             = "rbac"
+	CategoryNoServiceAccount = "no-sa"
+	CategoryNoSecret         = "no-secret"
+	CategoryCodefresh        = "codefresh-error"
+	CategoryTimeout          = "timeout"
+	CategoryOther            = "other"
 )
 
 type (
 	Reporter interface {
 		AddToReport(string, string, string)
+		AddEntry(ReportEntry)
+		Entries() []ReportEntry
+		// PrintableEntries are the entries that pass the filter with the redaction applied, as they are printed,
+		// reporters sending the entries elsewhere must use them instead of Entries
+		PrintableEntries() []ReportEntry
+		// Printable applies the filter and the redaction to a single entry, false when it is filtered out
+		Printable(ReportEntry) (ReportEntry, bool)
+		Summary() Summary
+		SetAuthTypes(map[string]int)
 		Print()
+		PrintText(io.Writer) error
+		PrintJSON(io.Writer) error
+		PrintJUnit(io.Writer) error
+		// Flush delivers the report to where it is sent once the run is done
+		Flush() error
 	}
 
+	// Summary counts the entries of the report by outcome
+	Summary struct {
+		Total     int `json:"total"`
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+		Skipped   int `json:"skipped"`
+		// AuthTypes counts the contexts by the kind of credentials of their user
+		AuthTypes map[string]int `json:"authTypes,omitempty"`
+		// FailureCategories counts the failed contexts by the category of the failure
+		FailureCategories map[string]int `json:"failureCategories,omitempty"`
+	}
+
+	// ReportEntry is the result of a single context
+	ReportEntry struct {
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Message string `json:"message,omitempty"`
+		// Meta holds additional information discovered while working on the context
+		Meta map[string]string `json:"meta,omitempty"`
+		// Category is why the context failed, empty for the other statuses
+		Category string `json:"category,omitempty"`
+	}
+
+	jsonReport struct {
+		Entries []ReportEntry `json:"entries"`
+		Summary Summary       `json:"summary"`
+	}
+
+	// reporter is safe for concurrent use, the contexts may be processed in parallel
 	reporter struct {
-		data []struct {
-			name    string
-			status  string
-			message string
-		}
+		mu        sync.Mutex
+		sorted    bool
+		data      []ReportEntry
+		authTypes map[string]int
+		redaction []*regexp.Regexp
+		filter    Predicate
 	}
+
+	// Predicate decides whether the entry is included in the printed report
+	Predicate func(ReportEntry) bool
+
+	// Option configures optional behaviour of the reporter
+	Option func(*reporter)
 )
 
-func NewReporter() Reporter {
-	return &reporter{}
+// RedactedPlaceholder replaces the parts of the report matched by the redaction patterns
+const RedactedPlaceholder = "[REDACTED]"
+
+// WithRedaction replaces everything matched by the patterns in the printed report,
+// the entries themselves are kept as is
+func WithRedaction(patterns []*regexp.Regexp) Option {
+	return func(r *reporter) {
+		r.redaction = patterns
+	}
+}
+
+// WithFilter prints only the entries the predicate returns true for, all the entries are still collected
+func WithFilter(predicate Predicate) Option {
+	return func(r *reporter) {
+		r.filter = predicate
+	}
+}
+
+// WithSortedEntries prints the entries sorted by name instead of in the order they were added,
+// so the report of a parallel run does not depend on the scheduling
+func WithSortedEntries() Option {
+	return func(r *reporter) {
+		r.sorted = true
+	}
+}
+
+// IsFailure returns true for the statuses of contexts that were not added,
+// or were added but failed the smoke test, and for runs that were aborted
+func IsFailure(status string) bool {
+	return status == FAILED || status == FAILED_CONFLICT || status == DEADLINE_EXCEEDED || status == UNHEALTHY ||
+		status == SMOKE_FAILED || status == INVALID_NAME || status == RUN_FAILED
+}
+
+// IsRegistered returns true for the statuses of contexts that were added to Codefresh or updated in it by the run
+func IsRegistered(status string) bool {
+	return status == SUCCESS || status == REFRESHED || status == VERIFIED ||
+		status == RUNNER_CONNECTED || status == RUNNER_PENDING
+}
+
+// OnlyFailed keeps the contexts that were not added
+func OnlyFailed(entry ReportEntry) bool {
+	return IsFailure(entry.Status)
+}
+
+// OnlySucceeded keeps the contexts that are in Codefresh, the clusters that were deleted on purpose
+// and the contexts that passed the dry run
+func OnlySucceeded(entry ReportEntry) bool {
+	return entry.Status == SUCCESS || entry.Status == UNCHANGED || entry.Status == HEALTHY ||
+		entry.Status == VERIFIED || entry.Status == REFRESHED || entry.Status == DELETED ||
+		entry.Status == RUNNER_CONNECTED || entry.Status == RUNNER_PENDING || entry.Status == DRY_RUN
+}
+
+// OnlySkipped keeps the contexts that were skipped on purpose
+func OnlySkipped(entry ReportEntry) bool {
+	return entry.Status == SKIPPED || entry.Status == SKIPPED_EXCLUDED
+}
+
+func NewReporter(opts ...Option) Reporter {
+	r := &reporter{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *reporter) AddToReport(contextName string, status string, message string) {
-	r.data = append(r.data, struct {
-		name    string
-		status  string
-		message string
-	}{
-		name:    contextName,
-		status:  status,
-		message: message,
+	r.AddEntry(ReportEntry{
+		Name:    contextName,
+		Status:  status,
+		Message: message,
+	})
+}
+
+func (r *reporter) AddEntry(entry ReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data = append(r.data, entry)
+}
+
+// Entries returns a copy of the collected entries, in the order they were added
+func (r *reporter) Entries() []ReportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ReportEntry{}, r.data...)
+}
+
+func (r *reporter) Summary() Summary {
+	s := Summarize(r.Entries())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.AuthTypes = r.authTypes
+	return s
+}
+
+// SetAuthTypes saves the counts of the contexts by auth type to be included in the summary
+func (r *reporter) SetAuthTypes(counts map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.authTypes = counts
+}
+
+// Summarize counts the entries by outcome
+func Summarize(entries []ReportEntry) Summary {
+	s := Summary{
+		Total: len(entries),
+	}
+	for _, e := range entries {
+		switch {
+		case OnlySucceeded(e):
+			s.Succeeded++
+		case OnlyFailed(e):
+			s.Failed++
+			if s.FailureCategories == nil {
+				s.FailureCategories = map[string]int{}
+			}
+			s.FailureCategories[failureCategory(e)]++
+		case OnlySkipped(e):
+			s.Skipped++
+		}
+	}
+	return s
+}
+
+// failureCategory is the category of the failed entry, entries reported without one are counted as other
+func failureCategory(e ReportEntry) string {
+	switch {
+	case e.Category != "":
+		return e.Category
+	case e.Status == DEADLINE_EXCEEDED:
+		return CategoryTimeout
+	default:
+		return CategoryOther
+	}
+}
+
+// FailureBreakdown describes the failures by category, the most frequent first, e.g. "30 failures: 25 connectivity, 5 rbac"
+func (s Summary) FailureBreakdown() string {
+	categories := []string{}
+	for category := range s.FailureCategories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		ci, cj := s.FailureCategories[categories[i]], s.FailureCategories[categories[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return categories[i] < categories[j]
+	})
+	parts := []string{}
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%d %s", s.FailureCategories[category], category))
+	}
+	return fmt.Sprintf("%d failures: %s", s.Failed, strings.Join(parts, ", "))
+}
+
+// SuccessRatio is the part of the contexts that was added out of the ones that were tried,
+// skipped contexts are not counted
+func (s Summary) SuccessRatio() float64 {
+	tried := s.Succeeded + s.Failed
+	if tried == 0 {
+		return 1
+	}
+	return float64(s.Succeeded) / float64(tried)
+}
+
+// Flush does nothing, the report is only printed
+func (r *reporter) Flush() error {
+	return nil
+}
+
+func (r *reporter) redact(s string) string {
+	for _, p := range r.redaction {
+		s = p.ReplaceAllString(s, RedactedPlaceholder)
+	}
+	return s
+}
+
+// PrintableEntries returns the entries that pass the filter with the redaction applied
+func (r *reporter) PrintableEntries() []ReportEntry {
+	entries := []ReportEntry{}
+	data := r.Entries()
+	if r.sorted {
+		sort.SliceStable(data, func(i, j int) bool {
+			return data[i].Name < data[j].Name
+		})
+	}
+	for _, d := range data {
+		if d, ok := r.Printable(d); ok {
+			entries = append(entries, d)
+		}
+	}
+	return entries
+}
+
+// Printable returns the redacted copy of the entry, false when the filter drops it
+func (r *reporter) Printable(d ReportEntry) (ReportEntry, bool) {
+	if r.filter != nil && !r.filter(d) {
+		return d, false
+	}
+	d.Name = r.redact(d.Name)
+	d.Message = r.redact(d.Message)
+	if len(d.Meta) > 0 {
+		meta := map[string]string{}
+		for k, v := range d.Meta {
+			meta[k] = r.redact(v)
+		}
+		d.Meta = meta
+	}
+	return d, true
+}
+
+// PrintJSON writes the printed entries and the summary of all the entries as a single JSON object
+func (r *reporter) PrintJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&jsonReport{
+		Entries: r.PrintableEntries(),
+		Summary: r.Summary(),
 	})
 }
 
 func (r *reporter) Print() {
-	for _, d := range r.data {
-		if d.status == SUCCESS {
-			fmt.Printf("Kubernetes context %s added to Codefresh\n", d.name)
+	r.PrintText(os.Stdout)
+}
+
+// PrintText writes the printed entries one line per context, as Print does to stdout
+func (r *reporter) PrintText(w io.Writer) error {
+	for _, d := range r.PrintableEntries() {
+		name := d.Name + formatMeta(d.Meta)
+		if d.Status == SUCCESS {
+			fmt.Fprintf(w, "Kubernetes context %s added to Codefresh\n", name)
+			continue
+		}
+
+		if d.Status == FAILED {
+			fmt.Fprintf(w, "Failed to add Kubernetes context %s to Codefresh.%s\n", name, d.Message)
+			continue
+		}
+
+		if d.Status == FAILED_CONFLICT {
+			fmt.Fprintf(w, "Failed to add Kubernetes context %s to Codefresh, cluster with the same name already exists, use --overwrite to replace it\n", name)
 			continue
 		}
 
-		if d.status == FAILED {
-			fmt.Printf("Failed to add Kubernetes context %s to Codefresh.%s\n", d.name, d.message)
+		if d.Status == DEADLINE_EXCEEDED {
+			fmt.Fprintf(w, "Timed out adding Kubernetes context %s to Codefresh.%s\n", name, d.Message)
 			continue
 		}
+
+		if d.Status == UNCHANGED {
+			fmt.Fprintf(w, "Kubernetes context %s is unchanged in Codefresh\n", name)
+			continue
+		}
+
+		if d.Status == ORPHANED {
+			fmt.Fprintf(w, "Codefresh cluster %s has no Kubernetes context.%s\n", name, d.Message)
+			continue
+		}
+
+		if d.Status == SKIPPED {
+			fmt.Fprintf(w, "Skipped Kubernetes context %s.%s\n", name, d.Message)
+			continue
+		}
+
+		if d.Status == HEALTHY {
+			fmt.Fprintf(w, "Codefresh agent of cluster %s is healthy\n", name)
+			continue
+		}
+
+		if d.Status == UNHEALTHY {
+			fmt.Fprintf(w, "Codefresh agent of cluster %s is not healthy.%s\n", name, d.Message)
+			continue
+		}
+
+		if d.Status == SKIPPED_EXCLUDED {
+			fmt.Fprintf(w, "Excluded Kubernetes context %s\n", name)
+			continue
+		}
+
+		if d.Status == INVALID_NAME {
+			fmt.Fprintf(w, "Kubernetes context %s was not added to Codefresh, its name is invalid.%s\n", name, d.Message)
+			continue
+		}
+
+		if d.Status == REFRESHED {
+			fmt.Fprintf(w, "Kubernetes context %s was not reachable by Codefresh, its token is refreshed\n", name)
+			continue
+		}
+
+		if d.Status == VERIFIED {
+			fmt.Fprintf(w, "Kubernetes context %s added to Codefresh and passed the smoke test\n", name)
+			continue
+		}
+
+		if d.Status == RUNNER_CONNECTED {
+			fmt.Fprintf(w, "Kubernetes context %s added to Codefresh and reached by the runner\n", name)
+			continue
+		}
+
+		if d.Status == RUNNER_PENDING {
+			fmt.Fprintf(w, "Kubernetes context %s added to Codefresh but not reached by the runner yet.%s\n", name, d.Message)
+			continue
+		}
+
+		if d.Status == DELETED {
+			fmt.Fprintf(w, "Codefresh cluster %s deleted\n", name)
+			continue
+		}
+
+		if d.Status == DRY_RUN {
+			fmt.Fprintf(w, "Kubernetes context %s would be added to Codefresh, dry run\n", name)
+			continue
+		}
+
+		if d.Status == RUN_FAILED {
+			fmt.Fprintf(w, "Run failed before processing the contexts, no context was added to Codefresh.%s\n", d.Message)
+			continue
+		}
+
+		if d.Status == SMOKE_FAILED {
+			fmt.Fprintf(w, "Kubernetes context %s added to Codefresh but failed the smoke test.%s\n", name, d.Message)
+			continue
+		}
+	}
+	return nil
+}
+
+func formatMeta(meta map[string]string) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	pairs := []string{}
+	for k, v := range meta {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
 	}
+	sort.Strings(pairs)
+	return fmt.Sprintf(" [%s]", strings.Join(pairs, " "))
 }
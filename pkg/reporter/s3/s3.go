@@ -0,0 +1,165 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/aws"
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+const (
+	// DefaultACL is the canned ACL of the uploaded report
+	DefaultACL = "private"
+	// DefaultUploadTimeout bounds the upload when WithUploadTimeout is not set
+	DefaultUploadTimeout = time.Minute
+
+	s3Service      = "s3"
+	sseAlgorithmS3 = "AES256"
+)
+
+type (
+	s3Reporter struct {
+		reporter.Reporter
+		bucket        string
+		keyPrefix     string
+		region        string
+		runID         string
+		acl           string
+		sse           bool
+		uploadTimeout time.Duration
+		config        aws.Config
+	}
+
+	// Option configures optional behaviour of the S3 reporter
+	Option func(*s3Reporter)
+)
+
+// WithBase collects the entries in the given reporter, so it still prints them with its own options
+func WithBase(base reporter.Reporter) Option {
+	return func(r *s3Reporter) {
+		r.Reporter = base
+	}
+}
+
+// WithRunID names the object after the id of the run, so it can be correlated with the logs of the run
+func WithRunID(id string) Option {
+	return func(r *s3Reporter) {
+		r.runID = id
+	}
+}
+
+// WithACL sets the canned ACL of the object, e.g. bucket-owner-full-control, default is private
+func WithACL(acl string) Option {
+	return func(r *s3Reporter) {
+		r.acl = acl
+	}
+}
+
+// WithServerSideEncryption encrypts the object with SSE-S3
+func WithServerSideEncryption(enabled bool) Option {
+	return func(r *s3Reporter) {
+		r.sse = enabled
+	}
+}
+
+// WithUploadTimeout bounds the upload, default is DefaultUploadTimeout
+func WithUploadTimeout(timeout time.Duration) Option {
+	return func(r *s3Reporter) {
+		r.uploadTimeout = timeout
+	}
+}
+
+// WithConfig reaches S3 with a custom endpoint, credentials or client, e.g. for S3 compatible stores.
+// With a custom endpoint the bucket is addressed in the path instead of the host name.
+func WithConfig(config aws.Config) Option {
+	return func(r *s3Reporter) {
+		r.config = config
+	}
+}
+
+// NewS3Reporter uploads the JSON report to <keyPrefix>/<run id>.json in the bucket on Flush.
+// The credentials are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and the optional AWS_SESSION_TOKEN
+// unless WithConfig sets them.
+func NewS3Reporter(bucket string, keyPrefix string, region string, opts ...Option) reporter.Reporter {
+	r := &s3Reporter{
+		Reporter:      reporter.NewReporter(),
+		bucket:        bucket,
+		keyPrefix:     keyPrefix,
+		region:        region,
+		acl:           DefaultACL,
+		uploadTimeout: DefaultUploadTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// key is the object key of the report of the run
+func (r *s3Reporter) key() string {
+	return path.Join(r.keyPrefix, r.runID+".json")
+}
+
+// objectURL addresses the bucket by host name on AWS and by path on a custom endpoint
+func (r *s3Reporter) objectURL() string {
+	if r.config.Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(r.config.Endpoint, "/"), r.bucket, r.key())
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", r.bucket, r.region, r.key())
+}
+
+// Flush uploads the report of the run to S3
+func (r *s3Reporter) Flush() error {
+	err := r.Reporter.Flush()
+	if err != nil {
+		return err
+	}
+	body := &bytes.Buffer{}
+	err = r.PrintJSON(body)
+	if err != nil {
+		return err
+	}
+	creds := r.config.Credentials
+	if creds == nil {
+		creds, err = aws.CredentialsFromEnv()
+		if err != nil {
+			return err
+		}
+	}
+	client := r.config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.uploadTimeout)
+	defer cancel()
+	payload := body.Bytes()
+	req, err := http.NewRequest("PUT", r.objectURL(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Content-Sha256", aws.HexSHA256(payload))
+	req.Header.Set("X-Amz-Acl", r.acl)
+	if r.sse {
+		req.Header.Set("X-Amz-Server-Side-Encryption", sseAlgorithmS3)
+	}
+	aws.Sign(req, payload, creds, r.region, s3Service, time.Now())
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("Failed to upload report to s3://%s/%s, status %d: %s", r.bucket, r.key(), res.StatusCode, data)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+type (
+	slackReporter struct {
+		reporter.Reporter
+		webhookURL       string
+		mentionOnFailure string
+		runLogURL        string
+		httpClient       *http.Client
+	}
+
+	// Option configures optional behaviour of the Slack reporter
+	Option func(*slackReporter)
+
+	message struct {
+		Text   string  `json:"text"`
+		Blocks []block `json:"blocks"`
+	}
+
+	block struct {
+		Type     string  `json:"type"`
+		Text     *text   `json:"text,omitempty"`
+		Elements []*text `json:"elements,omitempty"`
+	}
+
+	text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+)
+
+// WithBase collects the entries in the given reporter, so it still prints them with its own options
+func WithBase(base reporter.Reporter) Option {
+	return func(r *slackReporter) {
+		r.Reporter = base
+	}
+}
+
+// WithRunLogURL links the message to the log of the run
+func WithRunLogURL(url string) Option {
+	return func(r *slackReporter) {
+		r.runLogURL = url
+	}
+}
+
+// NewSlackReporter buffers the entries and posts a summary to the Slack incoming webhook on Flush.
+// mentionOnFailure is a user id (U...) or a user group id (S...) mentioned when any context failed.
+func NewSlackReporter(webhookURL string, mentionOnFailure string, opts ...Option) reporter.Reporter {
+	r := &slackReporter{
+		Reporter:         reporter.NewReporter(),
+		webhookURL:       webhookURL,
+		mentionOnFailure: mentionOnFailure,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Flush posts the summary of the run to Slack
+func (r *slackReporter) Flush() error {
+	err := r.Reporter.Flush()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(r.message())
+	if err != nil {
+		return err
+	}
+	res, err := r.httpClient.Post(r.webhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Failed to post report to Slack, status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (r *slackReporter) message() *message {
+	summary := r.Summary()
+	title := fmt.Sprintf("Stevedore run: %d succeeded, %d failed, %d skipped of %d contexts", summary.Succeeded, summary.Failed, summary.Skipped, summary.Total)
+	if summary.Failed > 0 && r.mentionOnFailure != "" {
+		title = fmt.Sprintf("%s %s", mention(r.mentionOnFailure), title)
+	}
+	rows := []string{}
+	for _, e := range r.PrintableEntries() {
+		rows = append(rows, fmt.Sprintf("%-40s %s", e.Name, e.Status))
+	}
+	msg := &message{
+		Text: title,
+		Blocks: []block{
+			{
+				Type: "section",
+				Text: &text{Type: "mrkdwn", Text: title},
+			},
+		},
+	}
+	if len(rows) > 0 {
+		msg.Blocks = append(msg.Blocks, block{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: fmt.Sprintf("```%s```", strings.Join(rows, "\n"))},
+		})
+	}
+	if r.runLogURL != "" {
+		msg.Blocks = append(msg.Blocks, block{
+			Type: "context",
+			Elements: []*text{
+				{Type: "mrkdwn", Text: fmt.Sprintf("<%s|Run log>", r.runLogURL)},
+			},
+		})
+	}
+	return msg
+}
+
+func mention(id string) string {
+	if strings.HasPrefix(id, "S") {
+		return fmt.Sprintf("<!subteam^%s>", id)
+	}
+	return fmt.Sprintf("<@%s>", id)
+}
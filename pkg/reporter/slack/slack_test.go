@@ -0,0 +1,70 @@
+package slack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+func TestFlushPostsRedactedFilteredEntries(t *testing.T) {
+	var received message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.Unmarshal(data, &received)
+		if err != nil {
+			t.Fatalf("invalid payload %s: %s", data, err)
+		}
+	}))
+	defer server.Close()
+
+	base := reporter.NewReporter(
+		reporter.WithRedaction([]*regexp.Regexp{regexp.MustCompile(`secret-[a-z]+`)}),
+		reporter.WithFilter(reporter.OnlyFailed),
+	)
+	r := NewSlackReporter(server.URL, "U123", WithBase(base))
+	r.AddToReport("ctx-secret-prod", reporter.FAILED, "token secret-abc rejected")
+	r.AddToReport("ctx-ok", reporter.SUCCESS, "")
+
+	err := r.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Blocks) < 2 {
+		t.Fatalf("expected the title and the rows, got %+v", received.Blocks)
+	}
+	if !strings.HasPrefix(received.Text, "<@U123>") {
+		t.Errorf("expected the mention on failure, got %q", received.Text)
+	}
+	rows := received.Blocks[1].Text.Text
+	if strings.Contains(rows, "secret-prod") {
+		t.Errorf("redacted name posted: %s", rows)
+	}
+	if !strings.Contains(rows, reporter.RedactedPlaceholder) {
+		t.Errorf("expected the redacted name, got %s", rows)
+	}
+	if strings.Contains(rows, "ctx-ok") {
+		t.Errorf("filtered out context posted: %s", rows)
+	}
+}
+
+func TestFlushFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	r := NewSlackReporter(server.URL, "")
+	r.AddToReport("ctx", reporter.SUCCESS, "")
+	if err := r.Flush(); err == nil {
+		t.Fatal("expected an error for status 500")
+	}
+}
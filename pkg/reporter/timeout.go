@@ -0,0 +1,38 @@
+package reporter
+
+import (
+	"context"
+	"time"
+)
+
+// timeoutReporter bounds the time Flush of the inner reporter may take
+type timeoutReporter struct {
+	Reporter
+	flushTimeout time.Duration
+}
+
+// NewTimeoutReporter returns context.DeadlineExceeded from Flush when the inner reporter does not finish
+// flushing within flushTimeout, so a slow file or network reporter does not block the exit.
+// The inner flush is left running in the background. The rest of the methods are not bounded.
+func NewTimeoutReporter(inner Reporter, flushTimeout time.Duration) Reporter {
+	return &timeoutReporter{
+		Reporter:     inner,
+		flushTimeout: flushTimeout,
+	}
+}
+
+// Flush waits for the inner flush up to the timeout
+func (r *timeoutReporter) Flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.flushTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Reporter.Flush()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
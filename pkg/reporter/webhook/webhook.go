@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxBufferSize = 100
+	defaultTimeout       = 10 * time.Second
+
+	flushRetries      = 4
+	flushInitialDelay = time.Second
+)
+
+type (
+	// WebhookReporterOptions configures the delivery of the events
+	WebhookReporterOptions struct {
+		// MaxBufferSize is the number of failed events kept for Flush, the oldest are dropped, default 100
+		MaxBufferSize int
+		// Timeout bounds a single POST, default 10s
+		Timeout time.Duration
+		// BearerToken is sent in the Authorization header, optional
+		BearerToken string
+		// Base collects the entries, so it still prints them with its own options, optional
+		Base reporter.Reporter
+	}
+
+	webhookReporter struct {
+		reporter.Reporter
+		endpoint   string
+		opts       WebhookReporterOptions
+		httpClient *http.Client
+
+		inFlight sync.WaitGroup
+
+		mutex   sync.Mutex
+		buffer  []*event
+		dropped int
+	}
+
+	// event is the body of the POST, the entry of the context with the time it was reported at
+	event struct {
+		reporter.ReportEntry
+		Time string `json:"time"`
+	}
+)
+
+// NewWebhookReporter posts every entry to the endpoint as soon as it is reported, without waiting for the run to finish.
+// The events that fail to be posted are buffered and retried with exponential backoff on Flush.
+func NewWebhookReporter(endpoint string, opts WebhookReporterOptions) reporter.Reporter {
+	if opts.MaxBufferSize <= 0 {
+		opts.MaxBufferSize = defaultMaxBufferSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	base := opts.Base
+	if base == nil {
+		base = reporter.NewReporter()
+	}
+	return &webhookReporter{
+		Reporter: base,
+		endpoint: endpoint,
+		opts:     opts,
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+	}
+}
+
+// AddToReport adds the entry and posts it
+func (r *webhookReporter) AddToReport(contextName string, status string, message string) {
+	r.AddEntry(reporter.ReportEntry{
+		Name:    contextName,
+		Status:  status,
+		Message: message,
+	})
+}
+
+// AddEntry adds the entry and posts it in the background, with the filter and the redaction of the base applied
+func (r *webhookReporter) AddEntry(entry reporter.ReportEntry) {
+	r.Reporter.AddEntry(entry)
+	entry, ok := r.Reporter.Printable(entry)
+	if !ok {
+		return
+	}
+	e := &event{
+		ReportEntry: entry,
+		Time:        time.Now().UTC().Format(time.RFC3339),
+	}
+	r.inFlight.Add(1)
+	go func() {
+		defer r.inFlight.Done()
+		err := r.post(e)
+		if err != nil {
+			log.WithField("context_name", entry.Name).Warn(fmt.Sprintf("Failed to post report entry to webhook, retrying on flush, error:\n%s", err))
+			r.bufferEvent(e)
+		}
+	}()
+}
+
+// bufferEvent keeps the event for Flush, dropping the oldest one when the buffer is full
+func (r *webhookReporter) bufferEvent(e *event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.buffer = append(r.buffer, e)
+	if len(r.buffer) > r.opts.MaxBufferSize {
+		r.buffer = r.buffer[len(r.buffer)-r.opts.MaxBufferSize:]
+		r.dropped++
+	}
+}
+
+// Flush waits for the events in flight and retries the buffered ones
+func (r *webhookReporter) Flush() error {
+	err := r.Reporter.Flush()
+	if err != nil {
+		return err
+	}
+	r.inFlight.Wait()
+	r.mutex.Lock()
+	pending, dropped := r.buffer, r.dropped
+	r.buffer, r.dropped = nil, 0
+	r.mutex.Unlock()
+	if dropped > 0 {
+		log.Warn(fmt.Sprintf("Dropped %d report events, the webhook buffer is full", dropped))
+	}
+	delay := flushInitialDelay
+	for attempt := 0; attempt <= flushRetries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		failed := []*event{}
+		for _, e := range pending {
+			err = r.post(e)
+			if err != nil {
+				failed = append(failed, e)
+			}
+		}
+		pending = failed
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("Failed to post %d report events to webhook, last error:\n%s", len(pending), err)
+	}
+	return nil
+}
+
+func (r *webhookReporter) post(e *event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", r.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.opts.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.opts.BearerToken)
+	}
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Failed to post report event to webhook, status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/codefresh-io/stevedore/pkg/reporter"
+)
+
+func TestPostsRedactedFilteredEntries(t *testing.T) {
+	var mu sync.Mutex
+	received := []event{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected authorization %q", req.Header.Get("Authorization"))
+		}
+		data, _ := ioutil.ReadAll(req.Body)
+		e := event{}
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Errorf("invalid payload %s: %s", data, err)
+		}
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	base := reporter.NewReporter(
+		reporter.WithRedaction([]*regexp.Regexp{regexp.MustCompile(`secret-[a-z]+`)}),
+		reporter.WithFilter(reporter.OnlyFailed),
+	)
+	r := NewWebhookReporter(server.URL, WebhookReporterOptions{BearerToken: "token", Base: base})
+	r.AddToReport("ctx-failed", reporter.FAILED, "token secret-abc rejected")
+	r.AddToReport("ctx-ok", reporter.SUCCESS, "")
+
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected only the failed entry, got %+v", received)
+	}
+	if received[0].Name != "ctx-failed" || strings.Contains(received[0].Message, "secret-abc") {
+		t.Errorf("expected the redacted failed entry, got %+v", received[0])
+	}
+	if len(r.Entries()) != 2 {
+		t.Errorf("expected both entries to be collected, got %d", len(r.Entries()))
+	}
+}
@@ -1,18 +1,99 @@
 package stevedore
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	awssign "github.com/codefresh-io/stevedore/pkg/aws"
 	"github.com/codefresh-io/stevedore/pkg/codefresh"
 	"github.com/codefresh-io/stevedore/pkg/kubernetes"
+	"github.com/codefresh-io/stevedore/pkg/kubernetes/aws"
+	"github.com/codefresh-io/stevedore/pkg/kubernetes/crd"
 	"github.com/codefresh-io/stevedore/pkg/reporter"
+	"github.com/codefresh-io/stevedore/pkg/reporter/email"
+	"github.com/codefresh-io/stevedore/pkg/reporter/gcs"
+	"github.com/codefresh-io/stevedore/pkg/reporter/s3"
+	"github.com/codefresh-io/stevedore/pkg/reporter/slack"
+	"github.com/codefresh-io/stevedore/pkg/reporter/webhook"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeClient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
-func Init(c *cli.Context) {
+func Init(c *cli.Context) error {
 	var name string
-	codefreshAPI := codefresh.NewCodefreshAPI(c.String("api-host"), c.String("token"))
-	reporter := reporter.NewReporter()
-	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter)
+	// a single cluster can take over the previous name, the contexts file would rename each of its contexts
+	if c.IsSet("rename-from") && (!c.IsSet("context") || c.IsSet("all") || c.IsSet("contexts-file")) {
+		return cli.NewExitError("--rename-from requires --context and can not be combined with --all or --contexts-file", 1)
+	}
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	runID := c.String("run-id")
+	if runID == "" {
+		runID = kubernetes.NewRunID()
+	}
+	reporter, err := newReporter(c, runID)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	opts, err := kubernetesOptions(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	opts = append(opts, kubernetes.WithRunID(runID))
+	if c.Bool("watch-config") && c.IsSet("config-ssm-parameter") {
+		return cli.NewExitError("--watch-config can not be used with a kubeconfig read from SSM", 1)
+	}
+	var kubernetesAPI kubernetes.API
+	if c.IsSet("config-ssm-parameter") {
+		kubernetesAPI, err = aws.NewKubernetesAPIFromSSM(context.Background(), c.String("config-ssm-parameter"), c.String("aws-region"), codefreshAPI, reporter, opts...)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to read kubeconfig from SSM with error:\n%s", err), 1)
+		}
+	} else {
+		kubernetesAPI, err = kubernetes.NewKubernetesAPIFromFile(c.String("config"), codefreshAPI, reporter, opts...)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load kubeconfig with error:\n%s", err), 1)
+		}
+	}
+	if c.Bool("watch-config") {
+		watcher, err := kubernetes.NewConfigWatcher(kubernetesAPI, c.String("config"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err = watcher.Start(ctx)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to watch kubeconfig with error:\n%s", err), 1)
+		}
+	}
+	if c.IsSet("output-contexts") {
+		err = kubernetesAPI.PrintContextList(os.Stdout, c.String("output-contexts"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		return nil
+	}
 	runOnAllContexts := c.IsSet("all")
 	runOnContext := c.String("context")
 	if c.IsSet("name-overwrite") {
@@ -20,13 +101,598 @@ func Init(c *cli.Context) {
 	} else {
 		name = runOnContext
 	}
-	if runOnAllContexts {
+	if runOnAllContexts && c.Bool("group-by-server") {
+		kubernetesAPI.GoOverContextGroups()
+	} else if runOnAllContexts {
 		kubernetesAPI.GoOverAllContexts()
+	} else if c.IsSet("contexts-file") {
+		contexts, err := kubernetes.LoadContextList(c.String("contexts-file"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load contexts file with error:\n%s", err), 1)
+		}
+		for _, contextName := range contexts {
+			kubernetesAPI.GoOverContextByName(contextName, c.String("namespace"), serviceAccounts(c), c.Bool("behind-firewall"), contextName)
+		}
 	} else if runOnContext != "" {
-		kubernetesAPI.GoOverContextByName(runOnContext, c.String("namespace"), c.String("serviceaccount"), c.Bool("behind-firewall"), name)
+		kubernetesAPI.GoOverContextByName(runOnContext, c.String("namespace"), serviceAccounts(c), c.Bool("behind-firewall"), name)
 	} else {
 		kubernetesAPI.GoOverCurrentContext()
 	}
-	reporter.Print()
+	err = reporter.Flush()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to send report with error:\n%s", err))
+	}
+	err = printReport(c, reporter)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	summary := reporter.Summary()
+	if summary.Failed > 0 {
+		log.Info(summary.FailureBreakdown())
+	}
+	threshold := c.Float64("min-success-ratio")
+	log.Info(fmt.Sprintf("Success ratio %.2f, required %.2f", summary.SuccessRatio(), threshold))
+	if summary.SuccessRatio() < threshold {
+		return cli.NewExitError(fmt.Sprintf("%d of %d contexts failed", summary.Failed, summary.Failed+summary.Succeeded), 1)
+	}
 	log.Info("Operation is done, check your account setting")
+	return nil
+}
+
+// serviceAccounts splits the comma separated --serviceaccount
+func serviceAccounts(c *cli.Context) []string {
+	names := []string{}
+	for _, name := range strings.Split(c.String("serviceaccount"), ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, "default")
+	}
+	return names
+}
+
+// printReport writes the report to each --output, or to stdout in the format set by --report-format
+func printReport(c *cli.Context, r reporter.Reporter) error {
+	outputs := []reporter.Output{}
+	for _, value := range c.StringSlice("output") {
+		output, err := reporter.ParseOutput(value)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, output)
+	}
+	if len(outputs) > 0 {
+		return reporter.PrintToOutputs(r, outputs)
+	}
+	switch format := c.String("report-format"); format {
+	case "", reporter.FormatText:
+		r.Print()
+		return nil
+	case reporter.FormatJSON, reporter.FormatJUnit:
+		err := reporter.PrintTo(r, format, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("Failed to print report with error:\n%s", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown --report-format value %s", format)
+	}
+}
+
+func newReporter(c *cli.Context, runID string) (reporter.Reporter, error) {
+	redaction := []*regexp.Regexp{}
+	for _, pattern := range c.StringSlice("redact") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to compile redaction pattern %s with error:\n%s", pattern, err)
+		}
+		redaction = append(redaction, re)
+	}
+	opts := []reporter.Option{
+		reporter.WithRedaction(redaction),
+	}
+	if c.Int("workers") > 1 || c.Int("max-concurrency") > 1 {
+		opts = append(opts, reporter.WithSortedEntries())
+	}
+	switch c.String("report-only") {
+	case "":
+	case "failed":
+		opts = append(opts, reporter.WithFilter(reporter.OnlyFailed))
+	case "succeeded":
+		opts = append(opts, reporter.WithFilter(reporter.OnlySucceeded))
+	case "skipped":
+		opts = append(opts, reporter.WithFilter(reporter.OnlySkipped))
+	default:
+		return nil, fmt.Errorf("Unknown --report-only value %s", c.String("report-only"))
+	}
+	r := reporter.NewReporter(opts...)
+	if c.Bool("progress") {
+		r = reporter.NewProgressBarReporter(r, os.Stdout)
+	}
+	if c.IsSet("slack-webhook") {
+		r = slack.NewSlackReporter(c.String("slack-webhook"), c.String("slack-mention"), slack.WithBase(r), slack.WithRunLogURL(c.String("run-log-url")))
+	}
+	if c.IsSet("webhook-url") {
+		r = webhook.NewWebhookReporter(c.String("webhook-url"), webhook.WebhookReporterOptions{
+			MaxBufferSize: c.Int("webhook-buffer-size"),
+			Timeout:       c.Duration("webhook-timeout"),
+			BearerToken:   c.String("webhook-token"),
+			Base:          r,
+		})
+	}
+	if c.IsSet("email-smtp-host") {
+		r = email.NewEmailReporter(email.EmailReporterOptions{
+			SMTPHost:   c.String("email-smtp-host"),
+			SMTPPort:   c.String("email-smtp-port"),
+			From:       c.String("email-from"),
+			To:         c.String("email-to"),
+			Subject:    c.String("email-subject"),
+			TLSEnabled: c.Bool("email-tls"),
+			Username:   c.String("email-username"),
+			Password:   c.String("email-password"),
+			Base:       r,
+		})
+	}
+	if c.IsSet("gcs-bucket") {
+		var credentials io.Reader
+		if c.IsSet("gcs-credentials") {
+			key, err := ioutil.ReadFile(c.String("gcs-credentials"))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read GCS credentials with error:\n%s", err)
+			}
+			credentials = bytes.NewReader(key)
+		}
+		r = gcs.NewGCSReporter(c.String("gcs-bucket"), c.String("gcs-object-path"), credentials, gcs.WithBase(r), gcs.WithRunID(runID))
+	}
+	if c.IsSet("s3-bucket") {
+		config := awssign.Config{
+			Endpoint: c.String("s3-endpoint"),
+		}
+		r = s3.NewS3Reporter(c.String("s3-bucket"), c.String("s3-key-prefix"), c.String("aws-region"),
+			s3.WithBase(r),
+			s3.WithRunID(runID),
+			s3.WithACL(c.String("s3-acl")),
+			s3.WithServerSideEncryption(c.Bool("s3-sse")),
+			s3.WithConfig(config),
+		)
+	}
+	if c.IsSet("report-flush-timeout") {
+		r = reporter.NewTimeoutReporter(r, c.Duration("report-flush-timeout"))
+	}
+	return r, nil
+}
+
+func kubernetesOptions(c *cli.Context) ([]kubernetes.Option, error) {
+	opts := []kubernetes.Option{
+		kubernetes.WithTeamNames(c.StringSlice("team")),
+		kubernetes.WithStorage(c.String("storage-class"), c.String("reclaim-policy")),
+		kubernetes.WithAgentNamespace(c.String("agent-namespace")),
+		kubernetes.WithAgentServiceAccount(c.String("agent-serviceaccount")),
+		kubernetes.WithContextTimeout(c.Duration("context-timeout")),
+		kubernetes.WithRunDeadline(c.Duration("run-deadline"), c.Bool("cancel-in-flight")),
+		kubernetes.WithWorkers(c.Int("workers")),
+		kubernetes.WithMaxConcurrency(c.Int("max-concurrency")),
+		kubernetes.WithContextEnvPrefix(c.String("context-env-prefix")),
+		kubernetes.WithExcludedContexts(c.StringSlice("exclude-context"), c.StringSlice("exclude-context-pattern")),
+		kubernetes.WithInsecureHosts(c.StringSlice("insecure-host")),
+		kubernetes.WithGlobalTLSCABundle(c.String("ca-bundle")),
+		kubernetes.WithSmokeTest(c.String("smoke-test-pipeline"), c.Duration("smoke-test-timeout")),
+		kubernetes.WithRunnerCheck(c.Bool("check-runner"), c.Duration("check-runner-timeout")),
+		kubernetes.WithCAConsistencyCheck(c.Bool("check-ca-consistency")),
+		kubernetes.WithStrictConfigValidation(c.Bool("strict-config")),
+		kubernetes.WithTokenRefresh(c.Bool("refresh-tokens")),
+		kubernetes.WithTimingCache(c.String("timing-cache")),
+		kubernetes.WithNodeCount(c.Bool("node-count")),
+		kubernetes.WithInClusterPaths(c.String("in-cluster-token-file"), c.String("in-cluster-ca-file")),
+		kubernetes.WithConfigLoadRetry(c.Int("config-load-attempts"), c.Duration("config-load-delay")),
+		kubernetes.WithResultsConfigMap(c.Bool("write-results"), c.String("results-namespace")),
+		kubernetes.WithShuffle(c.Bool("shuffle"), c.Int64("shuffle-seed")),
+		kubernetes.WithRetries(c.Int("retries"), c.Duration("retry-delay")),
+		kubernetes.WithProviderFilter(c.StringSlice("include-provider"), c.StringSlice("exclude-provider")),
+		kubernetes.WithLogDir(c.String("log-dir")),
+		kubernetes.WithBatchedLogs(c.String("log-mode") == "batched"),
+		kubernetes.WithTokenExpiry(c.Int64("token-expiry-seconds")),
+		kubernetes.WithTokenAudience(c.String("token-audience"), c.Bool("strict-token-audience")),
+		kubernetes.WithDebugMode(c.Bool("debug")),
+		kubernetes.WithForceOverwrite(c.Bool("overwrite")),
+		kubernetes.WithRenameFrom(c.String("rename-from")),
+		kubernetes.WithDryRun(c.Bool("dry-run")),
+		kubernetes.WithServiceAccount(c.String("namespace"), serviceAccounts(c)),
+		kubernetes.WithAutoCreateServiceAccount(c.Bool("create-serviceaccount"), c.Bool("cleanup-on-failure")),
+		kubernetes.WithServiceAccountClusterRole(c.String("serviceaccount-cluster-role")),
+	}
+	switch c.String("log-mode") {
+	case "", "streaming", "batched":
+	default:
+		return nil, fmt.Errorf("Unknown --log-mode value %s", c.String("log-mode"))
+	}
+	if c.IsSet("secret-field-selector") {
+		selector, err := fields.ParseSelector(c.String("secret-field-selector"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse --secret-field-selector with error:\n%s", err)
+		}
+		opts = append(opts, kubernetes.WithSecretFieldSelector(selector))
+	}
+	checks := []kubernetes.PreflightCheck{}
+	if c.Bool("preflight-check-token") {
+		checks = append(checks, kubernetes.CodefreshTokenValidation())
+	}
+	if c.IsSet("preflight-min-contexts") {
+		checks = append(checks, kubernetes.KubeconfigMinContexts(c.Int("preflight-min-contexts")))
+	}
+	if len(checks) > 0 {
+		opts = append(opts, kubernetes.WithPreflightCheck(kubernetes.All(checks...)))
+	}
+	if c.Bool("annotate-kubeconfig") {
+		if c.IsSet("config-ssm-parameter") {
+			return nil, fmt.Errorf("--annotate-kubeconfig can not be used with a kubeconfig read from SSM")
+		}
+		opts = append(opts, kubernetes.WithKubeconfigAnnotations(c.String("config")))
+	}
+	if c.IsSet("overrides") {
+		overrides, err := kubernetes.LoadContextOverrides(c.String("overrides"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load context overrides with error:\n%s", err)
+		}
+		opts = append(opts, kubernetes.WithContextOverrides(overrides))
+	}
+	if c.IsSet("state-file") {
+		store, err := kubernetes.LoadFingerprintStore(c.String("state-file"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load state file with error:\n%s", err)
+		}
+		opts = append(opts, kubernetes.WithFingerprintStore(store))
+	}
+	if c.IsSet("server-overrides") {
+		overrides, err := kubernetes.LoadServerOverrides(c.String("server-overrides"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load server overrides with error:\n%s", err)
+		}
+		opts = append(opts, kubernetes.WithServerOverrides(overrides))
+	}
+	if c.IsSet("name-map") {
+		names, err := kubernetes.LoadContextMap(c.String("name-map"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load name map with error:\n%s", err)
+		}
+		opts = append(opts, kubernetes.WithNameMap(names))
+	}
+	if c.IsSet("namespace-map") {
+		namespaces, err := kubernetes.LoadContextMap(c.String("namespace-map"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load namespace map with error:\n%s", err)
+		}
+		opts = append(opts, kubernetes.WithNamespaceMap(namespaces))
+	}
+	if c.IsSet("events-namespace") {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create in-cluster config for events with error:\n%s", err)
+		}
+		clientset, err := kubeClient.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		namespace := &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: c.String("events-namespace"),
+			},
+		}
+		opts = append(opts, kubernetes.WithEventRecorder(kubernetes.NewEventRecorder(clientset, "stevedore"), namespace))
+	}
+	if c.IsSet("target") {
+		targets := []kubernetes.RegistrationTarget{}
+		for _, value := range c.StringSlice("target") {
+			parts := strings.SplitN(value, "=", 2)
+			target := kubernetes.RegistrationTarget{
+				Scope: parts[0],
+			}
+			if target.Scope == "" {
+				return nil, fmt.Errorf("Target must be in the form of <team>=<namespace>,..., got %s", value)
+			}
+			if len(parts) == 2 {
+				for _, namespace := range strings.Split(parts[1], ",") {
+					if namespace = strings.TrimSpace(namespace); namespace != "" {
+						target.Namespaces = append(target.Namespaces, namespace)
+					}
+				}
+			}
+			targets = append(targets, target)
+		}
+		opts = append(opts, kubernetes.WithRegistrationTargets(targets))
+	}
+	if c.IsSet("name-pattern") || c.IsSet("name-max-length") {
+		var pattern *regexp.Regexp
+		if c.String("name-pattern") != "" {
+			re, err := regexp.Compile(c.String("name-pattern"))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to compile name pattern with error:\n%s", err)
+			}
+			pattern = re
+		}
+		opts = append(opts, kubernetes.WithNameValidation(pattern, c.Int("name-max-length")))
+	}
+	if c.IsSet("cluster-info") {
+		parts := strings.SplitN(c.String("cluster-info"), "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Cluster info must be in the form of <namespace>/<name>, got %s", c.String("cluster-info"))
+		}
+		opts = append(opts, kubernetes.WithClusterInfo(parts[0], parts[1], c.String("cluster-info-description-key")))
+	}
+	return opts, nil
+}
+
+func CreatePipelines(c *cli.Context) error {
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	reporter := reporter.NewReporter()
+	opts := []kubernetes.Option{}
+	if c.IsSet("name-map") {
+		names, err := kubernetes.LoadContextMap(c.String("name-map"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load name map with error:\n%s", err), 1)
+		}
+		opts = append(opts, kubernetes.WithNameMap(names))
+	}
+	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter, opts...)
+	err = kubernetesAPI.GoCreatePipelinesForAllContexts(c.String("template"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to create pipelines with error:\n%s", err), 1)
+	}
+	log.Info("Operation is done, check your pipelines")
+	return nil
+}
+
+func newCodefreshAPI(c *cli.Context) (codefresh.API, error) {
+	if c.IsSet("api-config-map") {
+		return codefreshAPIFromConfigMap(c.String("api-config-map"))
+	}
+	opts := codefresh.ClientOptions{
+		BaseURL:             c.String("api-host"),
+		Token:               c.String("token"),
+		Timeout:             c.Duration("api-timeout"),
+		DialTimeout:         c.Duration("api-dial-timeout"),
+		TLSHandshakeTimeout: c.Duration("api-tls-handshake-timeout"),
+		HTTPKeepAlive:       c.Duration("api-keep-alive"),
+		IdleConnTimeout:     c.Duration("api-idle-conn-timeout"),
+		APIVersionHeader:    c.String("api-version-header"),
+		AsyncMode:           c.Bool("api-async"),
+
+		RateLimitLowWatermark: c.Int("api-rate-limit-low-watermark"),
+		DebugHTTP:             c.Bool("api-debug-http"),
+	}
+	if c.IsSet("api-client-cert") || c.IsSet("api-client-key") {
+		cert, err := tls.LoadX509KeyPair(c.String("api-client-cert"), c.String("api-client-key"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load Codefresh API client certificate with error:\n%s", err)
+		}
+		opts.ClientCertificates = []tls.Certificate{cert}
+	}
+	switch c.String("api-version") {
+	case "", codefresh.APIVersionV1:
+	case codefresh.APIVersionV2:
+		if c.Int("api-clients") > 1 {
+			return nil, errors.New("--api-clients is supported only with --api-version v1")
+		}
+		opts.APIVersion = codefresh.APIVersionV2
+	default:
+		return nil, fmt.Errorf("Unknown --api-version value %s", c.String("api-version"))
+	}
+	if c.Int("api-clients") > 1 {
+		return codefresh.NewClientPool(opts, c.Int("api-clients")), nil
+	}
+	return codefresh.NewCodefreshAPI(opts), nil
+}
+
+// codefreshAPIFromConfigMap reads the client options from namespace/name ConfigMap of the cluster stevedore runs in
+func codefreshAPIFromConfigMap(ref string) (codefresh.API, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("API ConfigMap should be in the form of <namespace>/<name>, got %s", ref)
+	}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create in-cluster config with error:\n%s", err)
+	}
+	clientset, err := kubeClient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	api, err := codefresh.NewCodefreshAPIFromConfigMap(context.Background(), clientset, parts[1], parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read Codefresh API options from ConfigMap with error:\n%s", err)
+	}
+	return api, nil
+}
+
+// DeleteAll deletes the clusters of the kubeconfig from Codefresh, fails when any of them was not deleted
+func DeleteAll(c *cli.Context) error {
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	reporter := reporter.NewReporter()
+	opts := []kubernetes.Option{}
+	if c.IsSet("name-map") {
+		names, err := kubernetes.LoadContextMap(c.String("name-map"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load name map with error:\n%s", err), 1)
+		}
+		opts = append(opts, kubernetes.WithNameMap(names))
+	}
+	deleteOptions := kubernetes.DeleteAllOptions{
+		Interval:   c.Duration("interval"),
+		Retries:    c.Int("retries"),
+		RetryDelay: c.Duration("retry-delay"),
+	}
+	if c.IsSet("checkpoint") {
+		deleteOptions.Checkpoint, err = kubernetes.LoadDeleteCheckpoint(c.String("checkpoint"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load checkpoint with error:\n%s", err), 1)
+		}
+	}
+	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter, opts...)
+	err = kubernetesAPI.GoDeleteAllClusters(deleteOptions)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to list clusters with error:\n%s", err), 1)
+	}
+	reporter.Print()
+	if failed := reporter.Summary().Failed; failed > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d clusters were not deleted", failed), 1)
+	}
+	return nil
+}
+
+func ReportOrphans(c *cli.Context) error {
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	reporter := reporter.NewReporter()
+	opts := []kubernetes.Option{}
+	if c.IsSet("name-map") {
+		names, err := kubernetes.LoadContextMap(c.String("name-map"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load name map with error:\n%s", err), 1)
+		}
+		opts = append(opts, kubernetes.WithNameMap(names))
+	}
+	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter, opts...)
+	err = kubernetesAPI.GoReportOrphanedClusters(c.Bool("delete"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to list clusters with error:\n%s", err), 1)
+	}
+	reporter.Print()
+	return nil
+}
+
+// DiffReports prints the changes between two JSON reports written with --output json=<path>
+func DiffReports(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("Expected the previous and the current report", 1)
+	}
+	diff, err := reporter.DiffReportFiles(c.Args().Get(0), c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to compare reports with error:\n%s", err), 1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(diff)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if c.Bool("fail-on-regression") && diff.Regressed() {
+		return cli.NewExitError(fmt.Sprintf("%d contexts started failing", len(diff.NewlyFailing)), 1)
+	}
+	return nil
+}
+
+func Export(c *cli.Context) error {
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter.NewReporter())
+	config, err := kubernetesAPI.ExportAsKubeconfig(context.Background())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to export clusters with error:\n%s", err), 1)
+	}
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to serialize kubeconfig with error:\n%s", err), 1)
+	}
+	if c.String("output") == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	err = ioutil.WriteFile(c.String("output"), data, 0600)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to write kubeconfig with error:\n%s", err), 1)
+	}
+	return nil
+}
+
+func CheckAgents(c *cli.Context) error {
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	reporter := reporter.NewReporter()
+	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter)
+	err = kubernetesAPI.GoCheckAgentStatus(context.Background())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to list clusters with error:\n%s", err), 1)
+	}
+	reporter.Print()
+	if reporter.Summary().Failed > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d agents are not healthy", reporter.Summary().Failed), 1)
+	}
+	return nil
+}
+
+func CreateEnvironments(c *cli.Context) error {
+	selector, err := labels.Parse(c.String("namespace-selector"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to parse namespace selector with error:\n%s", err), 1)
+	}
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	reporter := reporter.NewReporter()
+	kubernetesAPI := kubernetes.NewKubernetesAPI(c.String("config"), codefreshAPI, reporter)
+	err = kubernetesAPI.RegisterNamespacesAsEnvironments(context.Background(), c.String("context"), selector, kubernetes.EnvConfig{
+		ClusterName: c.String("name"),
+		NamePrefix:  c.String("prefix"),
+	})
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to list namespaces with error:\n%s", err), 1)
+	}
+	reporter.Print()
+	return nil
+}
+
+// RunOperator reconciles the ClusterRegistration objects of the cluster stevedore runs in until it is interrupted
+func RunOperator(c *cli.Context) error {
+	codefreshAPI, err := newCodefreshAPI(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	opts, err := kubernetesOptions(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	kubeConfig, err := clientcmd.LoadFromFile(c.String("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to load kubeconfig with error:\n%s", err), 1)
+	}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to create in-cluster config with error:\n%s", err), 1)
+	}
+	clientset, err := kubeClient.NewForConfig(config)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	reconciler := &crd.CRDReconciler{
+		Client:     clientset.CoreV1().RESTClient(),
+		Namespace:  c.String("watch-namespace"),
+		KubeConfig: kubeConfig,
+		Codefresh:  codefreshAPI,
+		Interval:   c.Duration("interval"),
+		Options:    opts,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reconciler.Start(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	log.Info("Stopping operator")
+	return nil
 }